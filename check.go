@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"angrysoft.ovh/angry-deck/deck"
+	"angrysoft.ovh/angry-deck/streamdeck"
+)
+
+// check runs "angry-deck check [-model name] [config.yml]", which prints
+// the problems of the configuration and returns the exit code.
+func check(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	model := flags.String("model", "", "check against this model instead of the attached device: "+strings.Join(streamdeck.ModelNames(), ", "))
+	flags.Parse(args)
+	path := defaultConfig
+	if flags.NArg() > 0 {
+		path = flags.Arg(0)
+		// Flags may also follow the configuration.
+		flags.Parse(flags.Args()[1:])
+	}
+
+	err := deck.CheckConfig(path, *model)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(path + ": OK")
+	return 0
+}
@@ -0,0 +1,164 @@
+package deck
+
+import (
+	"image"
+	"log"
+	"reflect"
+	"time"
+
+	"angrysoft.ovh/angry-deck/page"
+	"angrysoft.ovh/angry-deck/streamdeck"
+)
+
+// animatedKey is a button with an animated icon. Its frames are rendered
+// and encoded for the device once and kept for the next visits of the page,
+// as long as the icon and the label they were rendered with stay the same.
+// Still icons have no frames.
+type animatedKey struct {
+	button  *page.Button
+	icon    page.Icon
+	label   page.Label
+	frames  []image.Image
+	delays  []time.Duration
+	images  []image.Image
+	encoded []*streamdeck.ImageData
+	badge   string
+	frame   int
+	next    time.Time
+}
+
+// startAnimation shows the first frame of an animated button on the current
+// page and schedules the next ones. It reports whether the button has an
+// animated icon. The caller must hold d.lock.
+func (d *Deck) startAnimation(pageName string, button *page.Button) bool {
+	key := buttonKey(pageName, button.Index)
+	ak, exists := d.animations[key]
+	if !exists || !ak.rendered(button) {
+		animation, err := d.deck.RenderAnimation(d.configDir, d.buttonBackground(button), button.Icon, button.TextLabels(button.Label)...)
+		if err != nil {
+			log.Println("Error rendering animation:", err.Error())
+		}
+		// Still icons are remembered too, so they are not read again.
+		ak = &animatedKey{button: button, icon: button.Icon, label: button.Label}
+		if animation != nil {
+			ak.frames, ak.delays = animation.Frames, animation.Delays
+		}
+		d.animations[key] = ak
+	}
+	if len(ak.frames) == 0 {
+		return false
+	}
+
+	ak.frame = 0
+	ak.next = time.Now().Add(ak.delays[0])
+	d.animated[button.Index] = ak
+	d.showFrame(ak)
+	d.wakeAnimations()
+	return true
+}
+
+// rendered reports whether the frames were rendered with the icon and the
+// label of the button.
+func (ak *animatedKey) rendered(button *page.Button) bool {
+	return reflect.DeepEqual(ak.icon, button.Icon) && reflect.DeepEqual(ak.label, button.Label)
+}
+
+// runAnimations is the animation scheduler. It sleeps until the next frame
+// of any animated key on the current page is due and draws the due frames.
+// Without animated keys, or while the deck is asleep, it waits to be woken
+// up by wakeAnimations.
+func (d *Deck) runAnimations() {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	for {
+		d.lock.Lock()
+		next := d.nextFrame()
+		d.lock.Unlock()
+		if !next.IsZero() {
+			timer.Reset(time.Until(next))
+		}
+		select {
+		case <-d.animationWake:
+			timer.Stop()
+		case <-timer.C:
+			d.drawFrames(time.Now())
+		}
+	}
+}
+
+// wakeAnimations makes the scheduler look at the animated keys again.
+func (d *Deck) wakeAnimations() {
+	select {
+	case d.animationWake <- struct{}{}:
+	default:
+	}
+}
+
+// nextFrame returns when the next frame is due, or the zero time when
+// nothing is animated. The caller must hold d.lock.
+func (d *Deck) nextFrame() time.Time {
+	var next time.Time
+	if d.asleep {
+		return next
+	}
+	for _, ak := range d.animated {
+		if next.IsZero() || ak.next.Before(next) {
+			next = ak.next
+		}
+	}
+	return next
+}
+
+func (d *Deck) drawFrames(now time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.asleep {
+		return
+	}
+	for _, ak := range d.animated {
+		if now.Before(ak.next) {
+			continue
+		}
+		ak.frame = (ak.frame + 1) % len(ak.frames)
+		ak.next = ak.next.Add(ak.delays[ak.frame])
+		// Skip ahead instead of catching up after a stall.
+		if ak.next.Before(now) {
+			ak.next = now.Add(ak.delays[ak.frame])
+		}
+		d.showFrame(ak)
+	}
+}
+
+// showFrame sends the current frame to the device. Frames are encoded again
+// only when the badge of the button changes. The caller must hold d.lock.
+func (d *Deck) showFrame(ak *animatedKey) {
+	index := ak.button.Index
+	badge := d.badgeText(ak.button)
+	if ak.encoded == nil || ak.badge != badge {
+		ak.images = make([]image.Image, len(ak.frames))
+		ak.encoded = make([]*streamdeck.ImageData, len(ak.frames))
+		for i, frame := range ak.frames {
+			img, err := d.withBadge(ak.button, frame)
+			if err != nil {
+				log.Println("Error drawing badge:", err.Error())
+			}
+			ak.images[i] = img
+			ak.encoded[i], err = d.deck.EncodeImage(img)
+			if err != nil {
+				log.Println("Error encoding animation frame:", err.Error())
+				ak.encoded = nil
+				return
+			}
+		}
+		ak.badge = badge
+	}
+
+	d.keyBases[index] = ak.frames[ak.frame]
+	if d.pressed[index] {
+		return
+	}
+	err := d.deck.SetEncodedImage(index, ak.images[ak.frame], ak.encoded[ak.frame])
+	if err != nil {
+		log.Println("Error setting animation frame:", err.Error())
+	}
+}
@@ -0,0 +1,107 @@
+package deck
+
+import (
+	"context"
+	"image"
+	"log"
+	"os/exec"
+	"time"
+
+	"angrysoft.ovh/angry-deck/page"
+	"angrysoft.ovh/angry-deck/widget"
+)
+
+// showKey sets the image of a button on the current page with its badge on
+// top. The image without the badge is kept, so the badge can change without
+// rendering the button again. A held key keeps its pressed look and shows
+// the image when it is released. The caller must hold d.lock.
+func (d *Deck) showKey(button *page.Button, img image.Image) {
+	d.keyBases[button.Index] = img
+	img, err := d.withBadge(button, img)
+	if err != nil {
+		log.Println("Error drawing badge:", err.Error())
+	}
+	set := d.deck.SetImage
+	if d.pressed[button.Index] {
+		set = d.deck.KeepImage
+	}
+	err = d.setKeys(button, img, set)
+	if err != nil {
+		log.Println("Error setting button image:", err.Error())
+	}
+}
+
+// withBadge draws the badge of the button on the image, if it has one with
+// text. The caller must hold d.lock.
+func (d *Deck) withBadge(button *page.Button, img image.Image) (image.Image, error) {
+	text := d.badgeText(button)
+	if text == "" {
+		return img, nil
+	}
+	badged, err := d.deck.DrawBadge(img, button.Badge.Corner, button.Badge.BadgeLabel(text))
+	if err != nil {
+		return img, err
+	}
+	return badged, nil
+}
+
+// badgeText returns the text of the badge of a button on the current page,
+// which is empty without a badge. The caller must hold d.lock.
+func (d *Deck) badgeText(button *page.Button) string {
+	if button.Badge == nil {
+		return ""
+	}
+	if len(button.Badge.Command) > 0 {
+		return d.badges[buttonKey(d.currentPage, button.Index)]
+	}
+	return button.Badge.Text
+}
+
+// runBadgeSync keeps the badge text of the button in sync with the badge
+// command until done is closed.
+func (d *Deck) runBadgeSync(done <-chan struct{}, pageName string, button *page.Button) {
+	ticker := time.NewTicker(button.Badge.RefreshInterval())
+	defer ticker.Stop()
+	for {
+		d.syncBadge(done, pageName, button)
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncBadge runs the badge command and shows its output in the badge. An
+// empty output or "0" hides the badge. Only the badge is redrawn, on top of
+// the last image of the button.
+func (d *Deck) syncBadge(done <-chan struct{}, pageName string, button *page.Button) {
+	args := button.Badge.Command
+	ctx, cancel := context.WithTimeout(context.Background(), button.Badge.RefreshInterval())
+	defer cancel()
+	output, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+	if err != nil {
+		log.Println("Error running badge command:", err.Error())
+		return
+	}
+	text := widget.FirstLine(string(output))
+	if text == "0" {
+		text = ""
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	key := buttonKey(pageName, button.Index)
+	if d.badges[key] == text {
+		return
+	}
+	d.badges[key] = text
+	select {
+	case <-done:
+		return
+	default:
+	}
+	if base, exists := d.keyBases[button.Index]; exists {
+		d.showKey(button, base)
+	}
+}
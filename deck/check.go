@@ -0,0 +1,83 @@
+package deck
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"angrysoft.ovh/angry-deck/page"
+	"angrysoft.ovh/angry-deck/streamdeck"
+	"gopkg.in/yaml.v3"
+)
+
+// CheckConfig loads the configuration and returns every problem found in
+// it, checked against the named device model or, when model is empty, the
+// attached device. Nothing is drawn on the device.
+func CheckConfig(path string, model string) error {
+	var device streamdeck.DeckDevice
+	if model != "" {
+		var err error
+		device, err = streamdeck.ModelDevice(model)
+		if err != nil {
+			return err
+		}
+	} else {
+		devices, err := streamdeck.FindDevices()
+		if err != nil {
+			return fmt.Errorf("cannot list devices: %w", err)
+		}
+		if len(devices) == 0 {
+			return errors.New("no Stream Deck devices found, name a model to check against")
+		}
+		device = devices[0]
+	}
+	log.Println("Checking against device:", device.Product, "with", device.Keys, "keys")
+	return newDeck(&device).loadConfig(path, true)
+}
+
+// check reports the problems of the deck configuration at path and of its
+// pages.
+func (d *Deck) check(path string, doc *yaml.Node, pages []*page.Page) error {
+	var errs []error
+	names := make(map[string]*page.Page, len(pages))
+	for _, p := range pages {
+		if other, exists := names[p.Name]; exists && p.Name != "" {
+			errs = append(errs, p.ErrorAt("name", "page %s is already defined in %s", p.Name, other.File()))
+			continue
+		}
+		names[p.Name] = p
+	}
+	if _, exists := names[d.Default]; !exists {
+		at := page.LookupNode(doc, "default")
+		if at == nil {
+			at = doc
+		}
+		errs = append(errs, page.NodeError(path, at, "default page %q is not defined", d.Default))
+	}
+
+	if style := d.Settings.Pagination.Style; style != "" {
+		if _, exists := d.Theme.Styles[style]; !exists {
+			at := page.LookupNode(page.LookupNode(page.LookupNode(doc, "settings"), "pagination"), "style")
+			if at == nil {
+				at = doc
+			}
+			errs = append(errs, page.NodeError(path, at, "unknown pagination style %q", style))
+		}
+	}
+
+	checker := &page.Checker{
+		Grid:  page.Grid{Columns: int(d.deck.Columns), Rows: int(d.deck.Rows), Keys: int(d.deck.Keys)},
+		Pages: make(map[string]bool, len(names)),
+		Theme: d.Theme,
+		FindIcon: func(icon page.Icon) error {
+			return d.deck.FindIcon(d.configDir, icon)
+		},
+	}
+	for name := range names {
+		checker.Pages[name] = true
+	}
+	for _, p := range pages {
+		errs = append(errs, p.Check(checker))
+	}
+	return errors.Join(errs...)
+}
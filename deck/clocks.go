@@ -0,0 +1,105 @@
+package deck
+
+import (
+	"cmp"
+	"log"
+	"time"
+
+	"angrysoft.ovh/angry-deck/page"
+	"angrysoft.ovh/angry-deck/widget"
+)
+
+// Holding an interactive clock key at least this long resets it.
+const clockResetHold = time.Second
+
+type clockButton struct {
+	page    string
+	button  *page.Button
+	clock   *widget.Clock
+	pressed time.Time
+}
+
+// startClocks starts ticking the clocks when there are any and they are
+// not ticking yet. The caller must hold d.lock.
+func (d *Deck) startClocks() {
+	if len(d.clocks) > 0 && !d.clocksRunning {
+		d.clocksRunning = true
+		go d.runClocks()
+	}
+}
+
+// runClocks ticks every clock once a second, on the second. Clocks keep
+// running on hidden pages but only the visible ones are redrawn.
+func (d *Deck) runClocks() {
+	for {
+		now := time.Now()
+		time.Sleep(now.Truncate(time.Second).Add(time.Second).Sub(now))
+		d.tickClocks(time.Now())
+	}
+}
+
+func (d *Deck) tickClocks(now time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for _, cb := range d.clocks {
+		// The action runs like the one of a key, so it can switch pages,
+		// which needs the lock held here.
+		if cb.clock.Tick(now) && cb.button.Clock.Action != nil {
+			go d.runAction(cb.button.Clock.Action)
+		}
+		if cb.page == d.currentPage {
+			d.drawClock(cb, now)
+		}
+	}
+}
+
+// handleClockKey starts, pauses or resets an interactive clock on the
+// current page. It reports whether the key event was consumed.
+func (d *Deck) handleClockKey(index uint8, pressed bool) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	cb, exists := d.clocks[buttonKey(d.currentPage, index)]
+	if !exists || !cb.clock.Interactive() {
+		return false
+	}
+	now := time.Now()
+	if pressed {
+		cb.pressed = now
+		return true
+	}
+	if now.Sub(cb.pressed) >= clockResetHold {
+		cb.clock.Reset()
+	} else {
+		cb.clock.Toggle(now)
+	}
+	d.drawClock(cb, now)
+	return true
+}
+
+// drawClock renders the clock text centred over the button. The caller must
+// hold d.lock.
+func (d *Deck) drawClock(cb *clockButton, now time.Time) {
+	icon := cb.button.Icon
+	if fill := cb.clock.Fill(); fill != "" {
+		icon.Fill = fill
+	}
+	img, err := d.render(cb.button, icon, cb.button.TextLabels(cb.button.Label)...)
+	if err != nil {
+		log.Println("Error rendering clock:", err.Error())
+		return
+	}
+
+	cfg := cb.button.Clock
+	img, err = d.deck.DrawLabel(img, page.Label{
+		Text:      cb.clock.Text(now),
+		FontSize:  cmp.Or(cfg.FontSize, cb.button.FontSize),
+		FontColor: cfg.FontColor,
+		Align:     "center",
+		AutoFit:   true,
+	})
+	if err != nil {
+		log.Println("Error drawing clock text:", err.Error())
+		return
+	}
+	d.showKey(cb.button, img)
+}
@@ -1,29 +1,60 @@
 package deck
 
 import (
+	"errors"
 	"fmt"
+	"image"
 	"log"
-	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"angrysoft.ovh/angry-deck/page"
 	"angrysoft.ovh/angry-deck/streamdeck"
-	"gopkg.in/yaml.v3"
+	"angrysoft.ovh/angry-deck/widget"
 )
 
 type Deck struct {
-	PagesConfigs []string `yaml:"pages_configs"`
-	Default      string
-	Settings     DeckSettings
-	pages        map[string]*page.Page
-	handlers     map[string]*page.Action
-	deck         *streamdeck.DeckDevice
-	configDir    string
-	currentPage  string
+	PagesConfigs  []string `yaml:"pages_configs"`
+	Default       string
+	Settings      DeckSettings
+	Theme         page.Theme
+	NightTheme    page.Theme `yaml:"night_theme"`
+	pages         map[string]*page.Page
+	handlers      map[string]*page.Action
+	clocks        map[string]*clockButton
+	states        map[string]*stateButton
+	widgets       map[string]*widgetButton
+	badges        map[string]string
+	pressed       map[uint8]bool
+	keyBases      map[uint8]image.Image
+	background    image.Image
+	spanOwners    map[uint8]uint8
+	animations    map[string]*animatedKey
+	animated      map[uint8]*animatedKey
+	animationWake chan struct{}
+	asleep        bool
+	night         bool
+	clocksRunning bool
+	deck          *streamdeck.DeckDevice
+	configPath    string
+	configDir     string
+	currentPage   string
+	pageDone      chan struct{}
+	lock          sync.Mutex
+	reloading     sync.Mutex
 }
 
 type DeckSettings struct {
-	Brightness int
+	Brightness         int
+	Font               string
+	FallbackFonts      []string `yaml:"fallback_fonts"`
+	IconDirs           []string `yaml:"icon_dirs"`
+	IconTheme          string   `yaml:"icon_theme"`
+	Transition         string
+	TransitionDuration time.Duration `yaml:"transition_duration"`
+	Night              *NightSettings
+	Pagination         page.Pagination
 }
 
 func NewDeck() *Deck {
@@ -45,36 +76,156 @@ func NewDeck() *Deck {
 	}
 
 	// defer device.Close()
+	return newDeck(&device)
+}
+
+func newDeck(device *streamdeck.DeckDevice) *Deck {
 	return &Deck{
-		PagesConfigs: []string{},
-		Settings:     DeckSettings{Brightness: 100},
-		pages:        make(map[string]*page.Page),
-		handlers:     make(map[string]*page.Action),
-		deck:         &device,
+		PagesConfigs:  []string{},
+		Settings:      DeckSettings{Brightness: 100},
+		pages:         make(map[string]*page.Page),
+		handlers:      make(map[string]*page.Action),
+		clocks:        make(map[string]*clockButton),
+		states:        make(map[string]*stateButton),
+		widgets:       make(map[string]*widgetButton),
+		badges:        make(map[string]string),
+		pressed:       make(map[uint8]bool),
+		keyBases:      make(map[uint8]image.Image),
+		spanOwners:    make(map[uint8]uint8),
+		animations:    make(map[string]*animatedKey),
+		animated:      make(map[uint8]*animatedKey),
+		animationWake: make(chan struct{}, 1),
+		deck:          device,
 	}
 }
 
 func (d *Deck) LoadDeck(path string) error {
-	d.configDir = filepath.Dir(path)
-	data, err := os.ReadFile(path)
+	err := d.loadConfig(path, false)
 	if err != nil {
 		return err
 	}
-	err = yaml.Unmarshal(data, d)
-	if err != nil {
+	d.lock.Lock()
+	d.setBrightness(d.brightness())
+	d.lock.Unlock()
+	d.setPage(d.Default)
+	d.lock.Lock()
+	d.startClocks()
+	d.lock.Unlock()
+	go d.runAnimations()
+	go d.runNightSwitch()
+	go d.watchConfig()
+
+	return nil
+}
+
+// loadConfig reads the deck configuration and its pages and checks them
+// against the device, without drawing anything. When strict, as for the
+// check command, every problem found fails the loading. Otherwise only the
+// files that cannot be read fail it: the other problems are logged and the
+// buttons they are on skipped, so the rest of the deck works.
+func (d *Deck) loadConfig(path string, strict bool) error {
+	d.configPath = path
+	d.configDir = filepath.Dir(path)
+	doc, err := page.DecodeFile(path, d)
+	if doc == nil {
 		return err
 	}
+	errs := []error{err}
 
-	d.deck.SetBrightness(uint8(d.Settings.Brightness))
+	if night := d.Settings.Night; night != nil {
+		d.night, err = night.isNight(time.Now())
+		switch {
+		case err != nil && night.Mode == "desktop":
+			// The desktop may not be up yet, runNightSwitch asks it again.
+			log.Println("Error checking night mode, starting in day mode:", err.Error())
+			d.night = false
+		case err != nil && strict:
+			return err
+		case err != nil:
+			log.Println("Error checking night mode, starting in day mode:", err.Error())
+			d.night = false
+		}
+	}
+	d.deck.SetDefaultFont(d.Settings.Font, d.configDir)
+	d.deck.SetFallbackFonts(d.Settings.FallbackFonts)
+	d.deck.SetIconDirs(d.Settings.IconDirs, d.configDir)
+	d.deck.SetIconTheme(d.Settings.IconTheme)
 
+	grid := page.Grid{Columns: int(d.deck.Columns), Rows: int(d.deck.Rows), Keys: int(d.deck.Keys)}
+	pages := make([]*page.Page, 0, len(d.PagesConfigs))
 	for _, pageName := range d.PagesConfigs {
 		page := page.NewPage()
 		err := page.LoadPage(filepath.Join(d.configDir, pageName))
+		if !page.Loaded() {
+			return err
+		}
+		if !strict {
+			// Skipped before the checks, a broken button takes no key
+			// another one wants.
+			page.SkipBroken(err)
+		}
+		errs = append(errs, err, page.Place(grid))
+		pages = append(pages, page)
+	}
+	errs = append(errs, d.check(path, doc, pages))
+	err = errors.Join(errs...)
+	switch {
+	case err != nil && strict:
+		return err
+	case err != nil:
+		log.Println("Skipping the buttons with problems:", err.Error())
+		pages = d.skipBroken(grid, pages, err)
+	}
+
+	var subPages []*page.Page
+	for _, page := range pages {
+		paginated, err := page.Paginate(grid, d.Settings.Pagination)
+		if err != nil && !strict && page.SkipBroken(err) > 0 {
+			log.Println("Skipping the buttons with problems:", err.Error())
+			page.Place(grid)
+			paginated, err = page.Paginate(grid, d.Settings.Pagination)
+		}
 		if err != nil {
 			return err
 		}
+		subPages = append(subPages, paginated...)
+	}
+	for _, page := range subPages {
+		err = page.ApplyTheme(d.pageTheme(page))
+		switch {
+		case err != nil && strict:
+			return err
+		case err != nil:
+			log.Println("Error applying theme:", err.Error())
+		}
 		d.pages[page.Name] = page
-		for _, button := range page.Buttons {
+		for i := range page.Buttons {
+			button := &page.Buttons[i]
+			if button.Clock != nil {
+				clock, err := widget.NewClock(*button.Clock)
+				switch {
+				case err != nil && strict:
+					return fmt.Errorf("page %s button %d: %w", page.Name, button.Index, err)
+				case err != nil:
+					log.Printf("Skipping page %s button %d: %v", page.Name, button.Index, err)
+					continue
+				}
+				d.clocks[buttonKey(page.Name, button.Index)] = &clockButton{
+					page:   page.Name,
+					button: button,
+					clock:  clock,
+				}
+			}
+			if button.Widget != nil {
+				d.widgets[buttonKey(page.Name, button.Index)] = &widgetButton{button: button}
+			}
+			if len(button.States) > 0 {
+				d.states[buttonKey(page.Name, button.Index)] = &stateButton{
+					page:   page.Name,
+					button: button,
+				}
+				continue
+			}
 			onState := "pressed"
 			if button.Action.OnRelease {
 				onState = "released"
@@ -82,14 +233,37 @@ func (d *Deck) LoadDeck(path string) error {
 			d.handlers[fmt.Sprintf("%s.%d.%s", page.Name, button.Index, onState)] = &button.Action
 		}
 	}
-	d.setPage(d.Default)
-
 	return nil
 }
 
+// skipBroken drops the buttons the problems in err are on and places the
+// pages again. A page named like an earlier one and an unknown pagination
+// style are dropped too.
+func (d *Deck) skipBroken(grid page.Grid, pages []*page.Page, err error) []*page.Page {
+	if _, exists := d.Theme.Styles[d.Settings.Pagination.Style]; !exists {
+		d.Settings.Pagination.Style = ""
+	}
+	names := make(map[string]bool, len(pages))
+	kept := pages[:0]
+	for _, p := range pages {
+		if names[p.Name] && p.Name != "" {
+			continue
+		}
+		names[p.Name] = true
+		if p.SkipBroken(err) > 0 {
+			// The problems left were logged with the others.
+			p.Place(grid)
+		}
+		kept = append(kept, p)
+	}
+	return kept
+}
+
 func (d *Deck) SetBrightness(brightness uint8) {
 	if d.deck != nil {
-		d.deck.SetBrightness(brightness)
+		d.lock.Lock()
+		defer d.lock.Unlock()
+		d.setBrightness(brightness)
 	}
 }
 
@@ -97,6 +271,11 @@ func (d *Deck) ListHandlers() {
 	for key, action := range d.handlers {
 		log.Println("Handler:", key, "Action Type:", action.Type, "Value:", action.Value)
 	}
+	for key, sb := range d.states {
+		for _, state := range sb.button.States {
+			log.Println("Handler:", key, "State:", state.Name, "Action Type:", state.Action.Type, "Value:", state.Action.Value)
+		}
+	}
 }
 
 func (d *Deck) Listen() {
@@ -108,6 +287,16 @@ func (d *Deck) Listen() {
 		}
 		for ev := range event {
 			println("Key event:", ev.Index, "Pressed:", ev.Pressed)
+			// Every key of a spanning button acts as the button.
+			ev.Index = d.keyOwner(ev.Index)
+			if ev.Pressed {
+				d.showPressed(ev.Index)
+			} else {
+				d.restorePressed(ev.Index)
+			}
+			if d.handleClockKey(ev.Index, ev.Pressed) || d.handleStateKey(ev.Index, ev.Pressed) {
+				continue
+			}
 			state := "released"
 			if ev.Pressed {
 				state = "pressed"
@@ -117,14 +306,7 @@ func (d *Deck) Listen() {
 			action, exists := d.getAction(actionTrigger)
 			log.Println("Action:", actionTrigger, exists)
 			if exists {
-				if action.Type == "set_page" {
-					err := d.setPage(action.Value[0])
-					if err != nil {
-						log.Println("Error setting page:", err.Error())
-					}
-				} else {
-					action.DoExec()
-				}
+				d.runAction(action)
 			}
 		}
 	}
@@ -144,6 +326,39 @@ func (d *Deck) Close() {
 	}
 }
 
+func (d *Deck) runAction(action *page.Action) {
+	if action.Type == "set_page" {
+		err := d.setPage(action.Value[0])
+		if err != nil {
+			log.Println("Error setting page:", err.Error())
+		}
+	} else {
+		action.DoExec()
+	}
+}
+
+// findButton returns the button at the given index of the page, or nil.
+func (d *Deck) findButton(pageName string, index uint8) *page.Button {
+	p, exists := d.pages[pageName]
+	if !exists {
+		return nil
+	}
+	return findButton(p, index)
+}
+
+func findButton(p *page.Page, index uint8) *page.Button {
+	for i := range p.Buttons {
+		if p.Buttons[i].Index == index {
+			return &p.Buttons[i]
+		}
+	}
+	return nil
+}
+
+func buttonKey(pageName string, index uint8) string {
+	return fmt.Sprintf("%s.%d", pageName, index)
+}
+
 func (d *Deck) getAction(key string) (*page.Action, bool) {
 	action, exists := d.handlers[key]
 	return action, exists
@@ -156,12 +371,87 @@ func (d *Deck) setPage(name string) error {
 		return nil
 	}
 	log.Println("Set page ", name)
+	// The new page is drawn off-screen and shown through the transition.
+	if d.transitionEnabled() {
+		from := d.deck.KeyImages()
+		d.deck.Hold()
+		defer func() {
+			err := d.deck.Transition(from, d.Settings.Transition, d.transitionDuration())
+			if err != nil {
+				log.Println("Error in page transition:", err.Error())
+			}
+		}()
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.drawPage(name, page)
+	return nil
+}
+
+// drawPage shows the page on the deck and starts its widgets. The caller
+// must hold d.lock.
+func (d *Deck) drawPage(name string, page *page.Page) {
+	// Stop the widgets of the previous page before drawing the new one.
+	if d.pageDone != nil {
+		close(d.pageDone)
+	}
+	d.pageDone = make(chan struct{})
 	d.deck.Clear()
 	d.currentPage = name
+	d.keyBases = make(map[uint8]image.Image)
+	d.animated = make(map[uint8]*animatedKey)
+	d.drawBackground(page)
 
-	for _, button := range page.Buttons {
+	for i := range page.Buttons {
+		button := &page.Buttons[i]
 		fmt.Println("Setting button", button.Index, "on page", name)
-		d.deck.SetButton(button.Index, d.configDir, button.Label, button.Icon)
+		d.startButton(name, button)
+		d.drawButton(name, button)
 	}
-	return nil
+}
+
+// startButton starts what keeps the button up to date while its page is
+// shown: its badge, widget, marquee and state commands. The caller must
+// hold d.lock.
+func (d *Deck) startButton(name string, button *page.Button) {
+	if button.Badge != nil && len(button.Badge.Command) > 0 {
+		go d.runBadgeSync(d.pageDone, name, button)
+	}
+	if wb, exists := d.widgets[buttonKey(name, button.Index)]; exists {
+		go d.runWidget(d.pageDone, wb)
+		return
+	}
+	if button.Clock == nil && button.HasMarquee() {
+		go d.runMarquee(d.pageDone, button)
+	}
+	if sb, exists := d.states[buttonKey(name, button.Index)]; exists && len(button.StateCommand) > 0 {
+		go d.runStateSync(d.pageDone, sb)
+	}
+}
+
+// drawButton draws the button on the page shown. Widgets are drawn by
+// their goroutines. The caller must hold d.lock.
+func (d *Deck) drawButton(name string, button *page.Button) {
+	if button.Widget != nil {
+		return
+	}
+	if cb, exists := d.clocks[buttonKey(name, button.Index)]; exists {
+		d.drawClock(cb, time.Now())
+		return
+	}
+	if sb, exists := d.states[buttonKey(name, button.Index)]; exists {
+		d.drawState(sb)
+		return
+	}
+	// Marquee buttons draw their own frames and animations cover a single
+	// key.
+	if !button.HasMarquee() && button.Span == nil && d.startAnimation(name, button) {
+		return
+	}
+	img, err := d.render(button, button.Icon, button.TextLabels(button.Label)...)
+	if err != nil {
+		log.Println("Error rendering button:", err.Error())
+		return
+	}
+	d.showKey(button, img)
 }
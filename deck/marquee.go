@@ -0,0 +1,117 @@
+package deck
+
+import (
+	"image"
+	"log"
+	"reflect"
+	"time"
+
+	"angrysoft.ovh/angry-deck/page"
+)
+
+// Marquee labels are redrawn at most this many times per second.
+const maxMarqueeFPS = 12
+
+// marquee is the animation state of a button with scrolling labels. The
+// button without the scrolling labels is rendered once per icon and label
+// change and the scrolling labels are drawn over it in every frame.
+type marquee struct {
+	button    *page.Button
+	icon      page.Icon
+	labels    []page.Label
+	base      image.Image
+	scrolling []page.Label
+	start     time.Time
+}
+
+// runMarquee scrolls the overflowing marquee labels of the button until done
+// is closed, which happens when the page is no longer visible.
+func (d *Deck) runMarquee(done <-chan struct{}, button *page.Button) {
+	m := &marquee{button: button, start: time.Now()}
+	ticker := time.NewTicker(marqueeInterval(button))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		d.drawMarquee(done, m, time.Now())
+	}
+}
+
+// marqueeInterval returns the frame interval: one pixel of the fastest
+// label per frame, capped at maxMarqueeFPS.
+func marqueeInterval(button *page.Button) time.Duration {
+	speed := 1
+	labels := button.TextLabels(button.Label)
+	for _, state := range button.States {
+		labels = append(labels, state.Label)
+	}
+	for _, label := range labels {
+		if label.Marquee {
+			speed = max(speed, label.ScrollSpeed())
+		}
+	}
+	return max(time.Second/time.Duration(speed), time.Second/maxMarqueeFPS)
+}
+
+func (d *Deck) drawMarquee(done <-chan struct{}, m *marquee, now time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	select {
+	case <-done:
+		return
+	default:
+	}
+	if d.pressed[m.button.Index] {
+		return
+	}
+
+	icon, label := m.button.Icon, m.button.Label
+	if sb, exists := d.states[buttonKey(d.currentPage, m.button.Index)]; exists {
+		icon, label = m.button.StateView(sb.state)
+	}
+	labels := m.button.TextLabels(label)
+	if m.base == nil || !reflect.DeepEqual(icon, m.icon) || !reflect.DeepEqual(labels, m.labels) {
+		if !d.renderMarquee(m, icon, labels) {
+			return
+		}
+	}
+	if len(m.scrolling) == 0 {
+		return
+	}
+
+	elapsed := now.Sub(m.start).Seconds()
+	img := m.base
+	for _, label := range m.scrolling {
+		var err error
+		img, err = d.deck.DrawMarquee(img, label, int(elapsed*float64(label.ScrollSpeed())))
+		if err != nil {
+			log.Println("Error drawing marquee:", err.Error())
+			return
+		}
+	}
+	d.showKey(m.button, img)
+}
+
+// renderMarquee renders the button without its overflowing marquee labels.
+// The caller must hold d.lock.
+func (d *Deck) renderMarquee(m *marquee, icon page.Icon, labels []page.Label) bool {
+	var static []page.Label
+	m.scrolling = nil
+	for _, label := range labels {
+		if d.deck.MarqueeOverflows(label) {
+			m.scrolling = append(m.scrolling, label)
+		} else {
+			static = append(static, label)
+		}
+	}
+	base, err := d.render(m.button, icon, static...)
+	if err != nil {
+		log.Println("Error rendering marquee button:", err.Error())
+		return false
+	}
+	m.icon, m.labels, m.base = icon, labels, base
+	return true
+}
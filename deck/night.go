@@ -0,0 +1,188 @@
+package deck
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"angrysoft.ovh/angry-deck/page"
+	"angrysoft.ovh/angry-deck/widget"
+)
+
+const (
+	defaultNightRefresh   = time.Minute
+	defaultDesktopRefresh = 10 * time.Second
+)
+
+// defaultColorSchemeCommand reads the colour scheme the desktop prefers
+// from the freedesktop settings portal. It prints 1 when it prefers dark.
+var defaultColorSchemeCommand = []string{
+	"gdbus", "call", "--session",
+	"--dest", "org.freedesktop.portal.Desktop",
+	"--object-path", "/org/freedesktop/portal/desktop",
+	"--method", "org.freedesktop.portal.Settings.Read",
+	"org.freedesktop.appearance", "color-scheme",
+}
+
+// NightSettings switches the deck to the night theme and brightness. Mode
+// is one of:
+//
+//   - schedule: from Start to End, local times like "21:00" and "07:00"
+//   - sun: from sunset to sunrise at Latitude and Longitude
+//   - desktop: while the desktop prefers a dark colour scheme, as printed by
+//     Command, which defaults to asking the freedesktop settings portal
+type NightSettings struct {
+	Mode       string
+	Start      string
+	End        string
+	Latitude   float64
+	Longitude  float64
+	Command    []string
+	Refresh    time.Duration
+	Brightness int
+}
+
+// isNight reports whether it is night at the given time.
+func (n *NightSettings) isNight(now time.Time) (bool, error) {
+	switch n.Mode {
+	case "schedule":
+		start, err := dayTime(now, n.Start)
+		if err != nil {
+			return false, err
+		}
+		end, err := dayTime(now, n.End)
+		if err != nil {
+			return false, err
+		}
+		if start.Before(end) {
+			return !now.Before(start) && now.Before(end), nil
+		}
+		return !now.Before(start) || now.Before(end), nil
+	case "sun":
+		sunrise, sunset, up := widget.SunTimes(now, n.Latitude, n.Longitude)
+		if sunrise.IsZero() {
+			return !up, nil
+		}
+		return now.Before(sunrise) || !now.Before(sunset), nil
+	case "desktop":
+		command := n.Command
+		if len(command) == 0 {
+			command = defaultColorSchemeCommand
+		}
+		output, err := exec.Command(command[0], command[1:]...).Output()
+		if err != nil {
+			return false, fmt.Errorf("cannot read the color scheme: %w", err)
+		}
+		return prefersDark(string(output)), nil
+	case "":
+		return false, errors.New("night mode is not set")
+	}
+	return false, fmt.Errorf("unknown night mode: %s", n.Mode)
+}
+
+// prefersDark reads the output of the portal, "(<<uint32 1>>,)" for dark,
+// or of a command printing the scheme name, like "'prefer-dark'".
+func prefersDark(output string) bool {
+	return strings.Contains(output, "uint32 1") || strings.Contains(strings.ToLower(output), "dark")
+}
+
+// dayTime returns the local time of the day of now given as "15:04".
+func dayTime(now time.Time, value string) (time.Time, error) {
+	t, err := time.ParseInLocation("15:04", value, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid night time %q: %w", value, err)
+	}
+	return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location()), nil
+}
+
+func (n *NightSettings) refreshInterval() time.Duration {
+	switch {
+	case n.Refresh > 0:
+		return n.Refresh
+	case n.Mode == "desktop":
+		return defaultDesktopRefresh
+	}
+	return defaultNightRefresh
+}
+
+// runNightSwitch checks every refresh interval whether it is night and
+// switches the theme and brightness when that changes. The night settings
+// are read each time, as reloading the configuration may change them.
+func (d *Deck) runNightSwitch() {
+	for {
+		interval := defaultNightRefresh
+		if settings := d.nightSettings(); settings != nil {
+			interval = settings.refreshInterval()
+		}
+		time.Sleep(interval)
+		settings := d.nightSettings()
+		if settings == nil {
+			continue
+		}
+		night, err := settings.isNight(time.Now())
+		if err != nil {
+			log.Println("Error checking night mode:", err.Error())
+			continue
+		}
+		d.setNight(night)
+	}
+}
+
+func (d *Deck) nightSettings() *NightSettings {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.Settings.Night
+}
+
+// setNight applies the day or the night theme to every page, redraws the
+// current page and sets the brightness.
+func (d *Deck) setNight(night bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.night == night {
+		return
+	}
+	log.Println("Switching to night mode:", night)
+	d.night = night
+	err := d.applyThemes()
+	if err != nil {
+		log.Println("Error applying theme:", err.Error())
+	}
+	// Animations keep their frames, which are drawn in the old theme.
+	d.animations = make(map[string]*animatedKey)
+	d.setBrightness(d.brightness())
+	if p, exists := d.pages[d.currentPage]; exists {
+		d.drawPage(d.currentPage, p)
+	}
+}
+
+// applyThemes gives every page its theme for the time of day. The caller
+// must hold d.lock.
+func (d *Deck) applyThemes() error {
+	var errs []error
+	for _, p := range d.pages {
+		errs = append(errs, p.ApplyTheme(d.pageTheme(p)))
+	}
+	return errors.Join(errs...)
+}
+
+// pageTheme returns the theme of the page, which inherits from the deck
+// theme. At night the night themes of the page and the deck are over it.
+func (d *Deck) pageTheme(p *page.Page) page.Theme {
+	theme := p.Theme.Inherit(d.Theme)
+	if d.night {
+		theme = p.NightTheme.Inherit(d.NightTheme).Inherit(theme)
+	}
+	return theme
+}
+
+// brightness returns the brightness for the time of day.
+func (d *Deck) brightness() uint8 {
+	if d.night && d.Settings.Night.Brightness > 0 {
+		return uint8(d.Settings.Night.Brightness)
+	}
+	return uint8(d.Settings.Brightness)
+}
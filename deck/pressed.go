@@ -0,0 +1,108 @@
+package deck
+
+import (
+	"image"
+	"log"
+
+	"angrysoft.ovh/angry-deck/page"
+	"angrysoft.ovh/angry-deck/streamdeck"
+)
+
+// showPressed swaps the key image for the pressed look of the button, if it
+// has one. The look is drawn by a goroutine, so the action of the key does
+// not wait for it. The normal image is kept by the device for
+// restorePressed.
+func (d *Deck) showPressed(index uint8) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	button := d.findButton(d.currentPage, index)
+	if button == nil || !button.HasPressedLook() {
+		return
+	}
+	d.pressed[index] = true
+	go d.drawPressed(d.pageDone, button)
+}
+
+// drawPressed shows the pressed look of the button unless the key was
+// released or the page left meanwhile.
+func (d *Deck) drawPressed(done <-chan struct{}, button *page.Button) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	select {
+	case <-done:
+		return
+	default:
+	}
+	if !d.pressed[button.Index] {
+		return
+	}
+	img, err := d.pressedImage(button)
+	if err == nil {
+		err = d.setKeys(button, img, d.deck.SetTemporaryImage)
+	}
+	if err != nil {
+		log.Println("Error showing pressed image:", err.Error())
+		d.restoreKeys(button.Index)
+	}
+}
+
+func (d *Deck) restorePressed(index uint8) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.pressed[index] {
+		d.restoreKeys(index)
+	}
+}
+
+// restoreKeys shows again the images kept for the keys of a pressed button.
+// The caller must hold d.lock.
+func (d *Deck) restoreKeys(index uint8) {
+	delete(d.pressed, index)
+	keys := []uint8{index}
+	if button := d.findButton(d.currentPage, index); button != nil {
+		keys = d.deck.SpanKeys(index, d.span(button))
+	}
+	for _, key := range keys {
+		err := d.deck.RestoreImage(key)
+		if err != nil {
+			log.Println("Error restoring key image:", err.Error())
+		}
+	}
+}
+
+// pressedImage renders the pressed icon or fill, or starts from the current
+// key image, and applies the badge and the pressed effect on top. The caller
+// must hold d.lock.
+func (d *Deck) pressedImage(button *page.Button) (image.Image, error) {
+	icon, label := button.Icon, button.Label
+	if sb, exists := d.states[buttonKey(d.currentPage, button.Index)]; exists {
+		icon, label = button.StateView(sb.state)
+	}
+
+	var img image.Image
+	var err error
+	switch {
+	case button.PressedIcon != nil:
+		img, err = d.render(button, *button.PressedIcon, button.TextLabels(label)...)
+	case button.PressedFill != "":
+		icon.Fill = button.PressedFill
+		img, err = d.render(button, icon, button.TextLabels(label)...)
+	default:
+		img = d.keyBases[button.Index]
+		if img == nil {
+			img, err = d.render(button, icon, button.TextLabels(label)...)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	img, err = d.withBadge(button, img)
+	if err != nil {
+		return nil, err
+	}
+
+	if button.PressedEffect != "" {
+		return streamdeck.PressedEffect(img, button.PressedEffect)
+	}
+	return img, nil
+}
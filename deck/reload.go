@@ -0,0 +1,277 @@
+package deck
+
+import (
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"angrysoft.ovh/angry-deck/page"
+	"angrysoft.ovh/angry-deck/streamdeck"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Editors often write a file in several steps, so changes are collected
+// this long before the configuration is reloaded.
+const reloadDelay = 200 * time.Millisecond
+
+// Reload reads the configuration again and swaps it in when its files can
+// be read. Buttons with problems are logged and skipped, like at start.
+// Otherwise the deck keeps running as it was.
+func (d *Deck) Reload() error {
+	return d.reload(false)
+}
+
+// reload loads the configuration into a new deck and swaps its pages,
+// handlers and settings in. When images changed, every key of the current
+// page is drawn again and the rendered animations are dropped.
+func (d *Deck) reload(images bool) error {
+	d.reloading.Lock()
+	defer d.reloading.Unlock()
+	log.Println("Reloading configuration:", d.configPath)
+
+	// The new configuration sets up a copy of the device, so one that fails
+	// to load leaves the device as it was.
+	d.lock.Lock()
+	device := *d.deck
+	d.lock.Unlock()
+	next := newDeck(&device)
+	err := next.loadConfig(d.configPath, false)
+	if err != nil {
+		return err
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.swap(next, images)
+	return nil
+}
+
+// swap replaces the configuration with the one loaded into next. Clocks,
+// multi-state buttons, widgets and animations that did not change are kept,
+// and the current page stays when it still exists. The caller must hold
+// d.lock.
+func (d *Deck) swap(next *Deck, images bool) {
+	animations := make(map[string]*animatedKey)
+	for name, p := range next.pages {
+		old := d.pages[name]
+		changed := changedButtons(old, p)
+		for i := range p.Buttons {
+			button := &p.Buttons[i]
+			key := buttonKey(name, button.Index)
+			if old == nil || changed[button.Index] {
+				continue
+			}
+			if prev, exists := d.clocks[key]; exists && next.clocks[key] != nil {
+				next.clocks[key].clock, next.clocks[key].pressed = prev.clock, prev.pressed
+			}
+			if prev, exists := d.states[key]; exists && next.states[key] != nil {
+				next.states[key].state = prev.state
+			}
+			if prev, exists := d.widgets[key]; exists && next.widgets[key] != nil {
+				next.widgets[key].metric, next.widgets[key].history = prev.takeState()
+			}
+			if ak, exists := d.animations[key]; exists && !images {
+				ak.button = button
+				animations[key] = ak
+			}
+		}
+	}
+
+	shown := d.pages[d.currentPage]
+	d.PagesConfigs = next.PagesConfigs
+	d.Default = next.Default
+	d.Settings = next.Settings
+	d.Theme, d.NightTheme = next.Theme, next.NightTheme
+	d.pages, d.handlers = next.pages, next.handlers
+	d.clocks, d.states, d.widgets = next.clocks, next.states, next.widgets
+	d.animations = animations
+	d.night = next.night
+
+	d.deck.SetDefaultFont(d.Settings.Font, d.configDir)
+	d.deck.SetFallbackFonts(d.Settings.FallbackFonts)
+	d.deck.SetIconDirs(d.Settings.IconDirs, d.configDir)
+	d.deck.SetIconTheme(d.Settings.IconTheme)
+	streamdeck.ResetCaches()
+	d.setBrightness(d.brightness())
+	d.startClocks()
+
+	p, exists := d.pages[d.currentPage]
+	switch {
+	case !exists:
+		if p, exists = d.pages[d.Default]; exists {
+			d.drawPage(d.Default, p)
+		}
+	case images || shown == nil:
+		d.drawPage(d.currentPage, p)
+	default:
+		d.refreshPage(shown, p)
+	}
+}
+
+// changedButtons returns the indices of the buttons that differ between
+// the old and the new page, including those on only one of them. All of
+// them changed when the page background did.
+func changedButtons(old, p *page.Page) map[uint8]bool {
+	changed := make(map[uint8]bool)
+	if old == nil {
+		for _, button := range p.Buttons {
+			changed[button.Index] = true
+		}
+		return changed
+	}
+	background := !reflect.DeepEqual(old.Background, p.Background)
+	for i := range old.Buttons {
+		button := &old.Buttons[i]
+		if background || !reflect.DeepEqual(button, findButton(p, button.Index)) {
+			changed[button.Index] = true
+		}
+	}
+	for i := range p.Buttons {
+		button := &p.Buttons[i]
+		if background || findButton(old, button.Index) == nil {
+			changed[button.Index] = true
+		}
+	}
+	return changed
+}
+
+// refreshPage draws again the changed keys of the page shown. The buttons
+// are restarted, as their goroutines must follow the new buttons, but the
+// unchanged ones are not drawn. The page is drawn as a whole when its
+// background changed or a changed button spans several keys. The caller
+// must hold d.lock.
+func (d *Deck) refreshPage(old, p *page.Page) {
+	if !reflect.DeepEqual(old.Background, p.Background) {
+		d.drawPage(d.currentPage, p)
+		return
+	}
+	changed := changedButtons(old, p)
+	for index := range changed {
+		for _, button := range []*page.Button{findButton(old, index), findButton(p, index)} {
+			if button != nil && d.span(button) != (image.Point{X: 1, Y: 1}) {
+				d.drawPage(d.currentPage, p)
+				return
+			}
+		}
+	}
+	log.Println("Redrawing", len(changed), "changed keys of page", d.currentPage)
+
+	close(d.pageDone)
+	d.pageDone = make(chan struct{})
+	for index := range changed {
+		delete(d.keyBases, index)
+		delete(d.animated, index)
+		if findButton(p, index) == nil {
+			d.clearKey(index)
+		}
+	}
+	for i := range p.Buttons {
+		button := &p.Buttons[i]
+		d.startButton(d.currentPage, button)
+		if changed[button.Index] {
+			d.drawButton(d.currentPage, button)
+		}
+	}
+}
+
+// clearKey shows the page background, or black, on a key without a button.
+// The caller must hold d.lock.
+func (d *Deck) clearKey(index uint8) {
+	var err error
+	if d.background != nil {
+		err = d.deck.SetImage(index, d.deck.GridArea(d.background, index, image.Point{X: 1, Y: 1}))
+	} else {
+		err = d.deck.ClearKey(index)
+	}
+	if err != nil {
+		log.Println("Error clearing key:", err.Error())
+	}
+}
+
+// watchConfig reloads the configuration when deck.yml, one of the page
+// files or a file in the images directory changes.
+func (d *Deck) watchConfig() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Println("Error watching configuration:", err.Error())
+		return
+	}
+	defer watcher.Close()
+	d.watchDirs(watcher)
+
+	var reload <-chan time.Time
+	images := false
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			config, icon := d.watched(event.Name)
+			if !config && !icon {
+				continue
+			}
+			images = images || icon
+			reload = time.After(reloadDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Println("Error watching configuration:", err.Error())
+		case <-reload:
+			reload = nil
+			err := d.reload(images)
+			images = false
+			if err != nil {
+				log.Println("Error reloading configuration:", err.Error())
+			}
+			// The page files may have moved to other directories.
+			d.watchDirs(watcher)
+		}
+	}
+}
+
+// watchDirs watches the directories of the configuration files and the
+// images directory. Files are replaced rather than written by many editors,
+// which a watch on the file itself would lose.
+func (d *Deck) watchDirs(watcher *fsnotify.Watcher) {
+	d.lock.Lock()
+	dirs := []string{filepath.Dir(d.configPath)}
+	for _, pageFile := range d.PagesConfigs {
+		dirs = append(dirs, filepath.Dir(filepath.Join(d.configDir, pageFile)))
+	}
+	imagesDir := filepath.Join(d.configDir, "images")
+	d.lock.Unlock()
+	if _, err := os.Stat(imagesDir); err == nil {
+		dirs = append(dirs, imagesDir)
+	}
+	for _, dir := range dirs {
+		err := watcher.Add(dir)
+		if err != nil {
+			log.Println("Error watching", dir+":", err.Error())
+		}
+	}
+}
+
+// watched reports whether the file is deck.yml or one of the page files,
+// or a file in the images directory.
+func (d *Deck) watched(file string) (config bool, images bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	file = filepath.Clean(file)
+	if file == filepath.Clean(d.configPath) {
+		return true, false
+	}
+	for _, pageFile := range d.PagesConfigs {
+		if file == filepath.Join(d.configDir, pageFile) {
+			return true, false
+		}
+	}
+	return false, filepath.Dir(file) == filepath.Join(d.configDir, "images")
+}
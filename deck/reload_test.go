@@ -0,0 +1,81 @@
+package deck
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"angrysoft.ovh/angry-deck/page"
+)
+
+// testPage returns a page with a button labelled with its text on each of
+// the keys.
+func testPage(labels map[uint8]string) *page.Page {
+	p := page.NewPage()
+	for _, index := range slices.Sorted(maps.Keys(labels)) {
+		p.Buttons = append(p.Buttons, page.Button{Index: index, Label: page.Label{Text: labels[index]}})
+	}
+	return p
+}
+
+func TestChangedButtons(t *testing.T) {
+	tests := []struct {
+		name string
+		old  *page.Page
+		p    *page.Page
+		want []uint8
+	}{
+		{
+			name: "new page",
+			p:    testPage(map[uint8]string{0: "a", 3: "b"}),
+			want: []uint8{0, 3},
+		},
+		{
+			name: "unchanged",
+			old:  testPage(map[uint8]string{0: "a", 1: "b"}),
+			p:    testPage(map[uint8]string{0: "a", 1: "b"}),
+		},
+		{
+			name: "changed label",
+			old:  testPage(map[uint8]string{0: "a", 1: "b"}),
+			p:    testPage(map[uint8]string{0: "a", 1: "c"}),
+			want: []uint8{1},
+		},
+		{
+			name: "added button",
+			old:  testPage(map[uint8]string{0: "a"}),
+			p:    testPage(map[uint8]string{0: "a", 2: "b"}),
+			want: []uint8{2},
+		},
+		{
+			name: "removed button",
+			old:  testPage(map[uint8]string{0: "a", 2: "b"}),
+			p:    testPage(map[uint8]string{0: "a"}),
+			want: []uint8{2},
+		},
+		{
+			name: "moved button",
+			old:  testPage(map[uint8]string{0: "a", 1: "b"}),
+			p:    testPage(map[uint8]string{0: "a", 4: "b"}),
+			want: []uint8{1, 4},
+		},
+		{
+			name: "background",
+			old:  testPage(map[uint8]string{0: "a", 1: "b"}),
+			p: func() *page.Page {
+				p := testPage(map[uint8]string{0: "a", 1: "b", 2: "c"})
+				p.Background = &page.Icon{Fill: "black"}
+				return p
+			}(),
+			want: []uint8{0, 1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slices.Sorted(maps.Keys(changedButtons(tt.old, tt.p)))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("changedButtons() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package deck
+
+import "log"
+
+// setBrightness sets the brightness of the display. The deck is asleep
+// while its display is off, at brightness 0, and animations pause until it
+// is turned on again. The caller must hold d.lock.
+func (d *Deck) setBrightness(brightness uint8) {
+	d.deck.SetBrightness(brightness)
+	asleep := brightness == 0
+	if asleep == d.asleep {
+		return
+	}
+	log.Println("Deck asleep:", asleep)
+	d.asleep = asleep
+	if !asleep {
+		d.wakeAnimations()
+	}
+}
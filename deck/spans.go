@@ -0,0 +1,102 @@
+package deck
+
+import (
+	"image"
+	"log"
+
+	"angrysoft.ovh/angry-deck/page"
+)
+
+// span returns the columns and rows of keys covered by the button, clipped
+// to the grid. Widgets cover a row of keys, a single one unless they are
+// charts.
+func (d *Deck) span(button *page.Button) image.Point {
+	if button.Widget != nil {
+		return d.deck.ClipSpan(button.Index, image.Point{X: button.Widget.Columns(), Y: 1})
+	}
+	if button.Span == nil {
+		return image.Point{X: 1, Y: 1}
+	}
+	return d.deck.ClipSpan(button.Index, image.Point{X: button.Span.Cols, Y: button.Span.Rows})
+}
+
+// render draws the button across its span, over the page background. The
+// caller must hold d.lock.
+func (d *Deck) render(button *page.Button, icon page.Icon, labels ...page.Label) (image.Image, error) {
+	return d.deck.RenderSpan(d.configDir, d.span(button), d.buttonBackground(button), icon, labels...)
+}
+
+// buttonBackground returns the part of the page background under the button,
+// or nil when the page has none. The caller must hold d.lock.
+func (d *Deck) buttonBackground(button *page.Button) image.Image {
+	if d.background == nil {
+		return nil
+	}
+	return d.deck.GridArea(d.background, button.Index, d.span(button))
+}
+
+// setKeys gives the image of a button to the keys it covers with set, one
+// of the methods of the device that set the image of a key. The caller must
+// hold d.lock.
+func (d *Deck) setKeys(button *page.Button, img image.Image, set func(uint8, image.Image) error) error {
+	span := d.span(button)
+	keys := d.deck.SpanKeys(button.Index, span)
+	images := []image.Image{img}
+	if len(keys) > 1 {
+		images = d.deck.SliceSpan(img, span)
+	}
+	for i, key := range keys {
+		err := set(key, images[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keyOwner returns the index of the button whose span covers the key on
+// the current page.
+func (d *Deck) keyOwner(index uint8) uint8 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if owner, exists := d.spanOwners[index]; exists {
+		return owner
+	}
+	return index
+}
+
+// drawBackground renders the page background and shows it on the keys that
+// no button covers. The caller must hold d.lock.
+func (d *Deck) drawBackground(p *page.Page) {
+	d.background = nil
+	d.spanOwners = make(map[uint8]uint8)
+	covered := make(map[uint8]bool)
+	for i := range p.Buttons {
+		button := &p.Buttons[i]
+		for _, key := range d.deck.SpanKeys(button.Index, d.span(button)) {
+			covered[key] = true
+			if key != button.Index {
+				d.spanOwners[key] = button.Index
+			}
+		}
+	}
+	if p.Background == nil {
+		return
+	}
+
+	background, err := d.deck.RenderBackground(d.configDir, *p.Background)
+	if err != nil {
+		log.Println("Error rendering page background:", err.Error())
+		return
+	}
+	d.background = background
+	for key := uint8(0); key < d.deck.Keys; key++ {
+		if covered[key] {
+			continue
+		}
+		err := d.deck.SetImage(key, d.deck.GridArea(background, key, image.Point{X: 1, Y: 1}))
+		if err != nil {
+			log.Println("Error setting background image:", err.Error())
+		}
+	}
+}
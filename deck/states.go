@@ -0,0 +1,115 @@
+package deck
+
+import (
+	"context"
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"angrysoft.ovh/angry-deck/page"
+)
+
+type stateButton struct {
+	page   string
+	button *page.Button
+	state  int
+}
+
+// handleStateKey runs the action of the current state of a multi-state
+// button and advances it to the next state. The state advances before the
+// action runs, so a state command or another press meanwhile starts from
+// the state the action was taken from. It reports whether the key event was
+// consumed.
+func (d *Deck) handleStateKey(index uint8, pressed bool) bool {
+	d.lock.Lock()
+	sb, exists := d.states[buttonKey(d.currentPage, index)]
+	if !exists {
+		d.lock.Unlock()
+		return false
+	}
+	action := sb.button.States[sb.state].Action
+	if action.OnRelease == pressed {
+		d.lock.Unlock()
+		return true
+	}
+	sb.state = (sb.state + 1) % len(sb.button.States)
+	if sb.page == d.currentPage {
+		d.drawState(sb)
+	}
+	d.lock.Unlock()
+
+	d.runAction(&action)
+	if len(sb.button.StateCommand) > 0 {
+		go d.syncState(sb)
+	}
+	return true
+}
+
+// runStateSync keeps the state of the button in sync with the state command
+// until done is closed.
+func (d *Deck) runStateSync(done <-chan struct{}, sb *stateButton) {
+	ticker := time.NewTicker(sb.button.StateRefreshInterval())
+	defer ticker.Stop()
+	for {
+		d.syncState(sb)
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncState runs the state command and selects the state whose name matches
+// its output. A number selects the state by its position.
+func (d *Deck) syncState(sb *stateButton) {
+	args := sb.button.StateCommand
+	ctx, cancel := context.WithTimeout(context.Background(), sb.button.StateRefreshInterval())
+	defer cancel()
+	output, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+	if err != nil {
+		log.Println("Error running state command:", err.Error())
+		return
+	}
+
+	value := strings.TrimSpace(string(output))
+	state := -1
+	for i, s := range sb.button.States {
+		if s.Name != "" && strings.EqualFold(s.Name, value) {
+			state = i
+			break
+		}
+	}
+	if state < 0 {
+		i, err := strconv.Atoi(value)
+		if err != nil || i < 0 || i >= len(sb.button.States) {
+			log.Printf("State command printed unknown state: %q\n", value)
+			return
+		}
+		state = i
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if state == sb.state {
+		return
+	}
+	sb.state = state
+	if sb.page == d.currentPage {
+		d.drawState(sb)
+	}
+}
+
+// drawState renders the button in its current state. The caller must hold
+// d.lock.
+func (d *Deck) drawState(sb *stateButton) {
+	icon, label := sb.button.StateView(sb.state)
+	img, err := d.render(sb.button, icon, sb.button.TextLabels(label)...)
+	if err != nil {
+		log.Println("Error rendering state:", err.Error())
+		return
+	}
+	d.showKey(sb.button, img)
+}
@@ -0,0 +1,24 @@
+package deck
+
+import "time"
+
+const defaultTransitionDuration = 300 * time.Millisecond
+
+// transitionEnabled reports whether page switches are animated. The first
+// page is shown without a transition.
+func (d *Deck) transitionEnabled() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	switch d.Settings.Transition {
+	case "", "none":
+		return false
+	}
+	return d.currentPage != "" && d.deck.SupportsTransitions()
+}
+
+func (d *Deck) transitionDuration() time.Duration {
+	if d.Settings.TransitionDuration <= 0 {
+		return defaultTransitionDuration
+	}
+	return d.Settings.TransitionDuration
+}
@@ -0,0 +1,148 @@
+package deck
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"angrysoft.ovh/angry-deck/page"
+	"angrysoft.ovh/angry-deck/streamdeck"
+	"angrysoft.ovh/angry-deck/widget"
+)
+
+// widgetButton keeps the metric of a widget button, and the history of a
+// chart, while its page is not shown, so they go on from where they were
+// when the page is shown again.
+type widgetButton struct {
+	button  *page.Button
+	metric  widget.Metric
+	history *widget.History
+	// lock is held while the widget is read, as the goroutine of a page
+	// left may still be reading when the page is shown again.
+	lock sync.Mutex
+}
+
+// read takes a reading of the metric, created on the first one, and adds it
+// to the history of a chart.
+func (wb *widgetButton) read() (widget.Reading, *widget.History, error) {
+	wb.lock.Lock()
+	defer wb.lock.Unlock()
+	if wb.metric == nil {
+		metric, err := widget.New(*wb.button.Widget)
+		if err != nil {
+			return widget.Reading{}, nil, err
+		}
+		wb.metric = metric
+		if wb.button.Widget.Display == "chart" {
+			wb.history = widget.NewHistory(wb.button.Widget.History)
+		}
+	}
+	reading, err := wb.metric.Read()
+	if err != nil {
+		return widget.Reading{}, nil, err
+	}
+	if wb.history == nil {
+		return reading, nil, nil
+	}
+	wb.history.Add(reading)
+	return reading, wb.history.Clone(), nil
+}
+
+// takeState returns the metric and history of the widget for the same
+// button of a reloaded configuration.
+func (wb *widgetButton) takeState() (widget.Metric, *widget.History) {
+	wb.lock.Lock()
+	defer wb.lock.Unlock()
+	return wb.metric, wb.history
+}
+
+// runWidget redraws the widget button at its refresh rate until done is
+// closed, which happens when the page is no longer visible.
+func (d *Deck) runWidget(done <-chan struct{}, wb *widgetButton) {
+	ticker := time.NewTicker(wb.button.Widget.RefreshInterval())
+	defer ticker.Stop()
+	for {
+		d.drawWidget(done, wb)
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Deck) drawWidget(done <-chan struct{}, wb *widgetButton) {
+	button := wb.button
+	reading, history, err := wb.read()
+	if err != nil {
+		log.Println("Error reading widget:", err.Error())
+		return
+	}
+	if history != nil {
+		d.drawChart(done, button, reading, history)
+		return
+	}
+	// The widget is rendered without the lock, which is only held to show
+	// it, so a slow widget does not hold up the other keys. Themes restyle
+	// the button under the lock, so its look is copied first.
+	d.lock.Lock()
+	span, background := d.span(button), d.buttonBackground(button)
+	icon, labels := button.Icon, button.TextLabels(button.Label)
+	valueLabel := button.ValueLabel(reading.Text)
+	d.lock.Unlock()
+	base, err := d.deck.RenderSpan(d.configDir, span, background, icon, labels...)
+	if err != nil {
+		log.Println("Error rendering widget:", err.Error())
+		return
+	}
+	img, err := d.deck.DrawMeter(base, streamdeck.Meter{
+		Label:   valueLabel,
+		Ratio:   reading.Ratio,
+		Display: button.Widget.Display,
+		Color:   button.Widget.ColorFor(reading.Value),
+	})
+	if err != nil {
+		log.Println("Error drawing widget:", err.Error())
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	select {
+	case <-done:
+		return
+	default:
+	}
+	d.showKey(button, img)
+}
+
+// drawChart draws the widget history across the keys the button spans.
+func (d *Deck) drawChart(done <-chan struct{}, button *page.Button, reading widget.Reading, history *widget.History) {
+	cfg := button.Widget
+	d.lock.Lock()
+	span, background := d.span(button), d.buttonBackground(button)
+	icon, labels := button.Icon, button.TextLabels(button.Label)
+	valueLabel := button.ValueLabel(reading.Text)
+	d.lock.Unlock()
+	img, err := d.deck.DrawChart(d.configDir, background, icon, streamdeck.Chart{
+		Points: history.Points(cfg.AutoScale()),
+		Size:   history.Size(),
+		Style:  cfg.ChartStyle,
+		Color:  cfg.ColorFor(reading.Value),
+		Label:  valueLabel,
+		Span:   span.X,
+	}, labels...)
+	if err != nil {
+		log.Println("Error drawing chart:", err.Error())
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	select {
+	case <-done:
+		return
+	default:
+	}
+	d.showKey(button, img)
+}
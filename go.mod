@@ -3,11 +3,17 @@ module angrysoft.ovh/angry-deck
 go 1.25.4
 
 require (
+	github.com/fsnotify/fsnotify v1.10.1
 	github.com/karalabe/hid v1.0.1-0.20190806082151-9c14560f9ee8
+	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
+	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef
 	golang.org/x/image v0.33.0
 )
 
+require golang.org/x/sys v0.38.0 // indirect
+
 require (
+	golang.org/x/net v0.47.0 // indirect
 	golang.org/x/text v0.31.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -13,6 +13,9 @@ const VERSION = "0.1.0"
 var defaultConfig = "example_config/deck.yml"
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		os.Exit(check(os.Args[2:]))
+	}
 	if len(os.Args) > 1 {
 		defaultConfig = os.Args[1]
 	}
@@ -28,14 +31,22 @@ func main() {
 	}()
 
 	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	go func() {
-		sig := <-sigs
-		println()
-		println("Received signal:", sig.String())
-		deck.Clear()
-		deck.Close()
-		os.Exit(1)
+		for sig := range sigs {
+			println()
+			println("Received signal:", sig.String())
+			if sig == syscall.SIGHUP {
+				err := deck.Reload()
+				if err != nil {
+					println("Error reloading deck:", err.Error())
+				}
+				continue
+			}
+			deck.Clear()
+			deck.Close()
+			os.Exit(1)
+		}
 	}()
 
 	deck.ListHandlers()
@@ -0,0 +1,46 @@
+package page
+
+import "time"
+
+const (
+	defaultBadgeRefresh  = 5 * time.Second
+	defaultBadgeFontSize = 9
+	defaultBadgeColor    = "#E53935"
+)
+
+// Badge is a short text in a pill in a corner of the button, for example an
+// unread count. The text comes from Command when it is set.
+type Badge struct {
+	Text       string
+	Corner     string
+	FontSize   int    `yaml:"font_size"`
+	FontColor  string `yaml:"font_color"`
+	Background string
+	Command    []string
+	Refresh    time.Duration
+}
+
+func (b *Badge) RefreshInterval() time.Duration {
+	if b.Refresh <= 0 {
+		return defaultBadgeRefresh
+	}
+	return b.Refresh
+}
+
+// BadgeLabel returns the label drawn in the badge with the given text, with
+// the default size and colour applied.
+func (b *Badge) BadgeLabel(text string) Label {
+	label := Label{
+		Text:       text,
+		FontSize:   b.FontSize,
+		FontColor:  b.FontColor,
+		Background: b.Background,
+	}
+	if label.FontSize == 0 {
+		label.FontSize = defaultBadgeFontSize
+	}
+	if label.Background == "" {
+		label.Background = defaultBadgeColor
+	}
+	return label
+}
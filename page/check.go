@@ -0,0 +1,230 @@
+package page
+
+import (
+	"errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Keys whose mapping values are icons.
+var iconKeys = map[string]bool{
+	"icon":         true,
+	"pressed_icon": true,
+	"background":   true,
+}
+
+// Checker is what a page is checked against: the key grid of the device,
+// the names of the pages of the deck, the deck theme and how icons are
+// found.
+type Checker struct {
+	Grid     Grid
+	Pages    map[string]bool
+	Theme    Theme
+	FindIcon func(Icon) error
+}
+
+// Check reports the problems of the page found when it is used: duplicate
+// indices, overlapping spans, unknown styles, invalid actions and missing
+// icons, each with the file, line and column it is on.
+// Run it after Place.
+func (p *Page) Check(c *Checker) error {
+	if p.doc == nil {
+		return nil
+	}
+	var errs []error
+	if p.Name == "" {
+		errs = append(errs, p.errorAt(p.doc, "page without a name"))
+	}
+	styles := p.Theme.Inherit(c.Theme).Styles
+	nodes := p.buttonNodes()
+	seen := make(map[uint8]*yaml.Node)
+	covered := make(map[int]*yaml.Node)
+	for i := range p.Buttons {
+		if i >= len(nodes) {
+			break
+		}
+		button, node := &p.Buttons[i], nodes[i]
+		index := field(node, "index")
+		if pos := LookupNode(node, "pos"); pos != nil {
+			index = pos
+		}
+		// Buttons that do not fit on the device go to the sub-pages, but two
+		// of them cannot have the same index.
+		first, exists := seen[button.Index]
+		switch {
+		case button.overflow && LookupNode(node, "index") == nil:
+		case exists:
+			errs = append(errs, p.errorAt(index, "duplicate index %d, also on line %d", button.Index, first.Line))
+		case button.overflow:
+			seen[button.Index] = index
+		default:
+			seen[button.Index] = index
+			errs = append(errs, p.checkSpan(c.Grid, button, node, covered)...)
+		}
+		if _, exists := styles[button.Style]; button.Style != "" && !exists {
+			errs = append(errs, p.errorAt(field(node, "style"), "unknown style %q", button.Style))
+		}
+		errs = append(errs, p.checkAction(c, button.Action, field(node, "action"))...)
+		if states := LookupNode(node, "states"); states != nil {
+			for j, state := range button.States {
+				if j < len(states.Content) {
+					errs = append(errs, p.checkAction(c, state.Action, field(states.Content[j], "action"))...)
+				}
+			}
+		}
+		if button.Clock != nil && button.Clock.Action != nil {
+			errs = append(errs, p.checkAction(c, *button.Clock.Action, field(field(node, "clock"), "action"))...)
+		}
+	}
+	if c.FindIcon != nil {
+		errs = append(errs, p.checkIcons(c, p.doc, "")...)
+	}
+	return errors.Join(errs...)
+}
+
+// SkipBroken removes the buttons of the page that the problems in err are
+// on, so the rest of the page can be used, and returns how many it removed.
+// Run Place again afterwards, so flowing buttons take the keys freed.
+func (p *Page) SkipBroken(err error) int {
+	nodes := p.buttonNodes()
+	broken := make([]bool, len(nodes))
+	for _, problem := range ConfigErrors(err) {
+		if problem.File != p.file {
+			continue
+		}
+		for i, node := range nodes {
+			broken[i] = broken[i] || atPosition(node, problem.Line, problem.Column)
+		}
+	}
+	var buttons []Button
+	var kept []*yaml.Node
+	for i, node := range nodes {
+		if i < len(p.Buttons) && !broken[i] {
+			buttons = append(buttons, p.Buttons[i])
+			kept = append(kept, node)
+		}
+	}
+	removed := len(p.Buttons) - len(buttons)
+	if removed > 0 {
+		p.Buttons = buttons
+		LookupNode(p.doc, "buttons").Content = kept
+	}
+	return removed
+}
+
+// atPosition reports whether the node, or a node in it, is at the line and
+// column.
+func atPosition(node *yaml.Node, line, column int) bool {
+	if node.Line == line && node.Column == column {
+		return true
+	}
+	for _, child := range node.Content {
+		if atPosition(child, line, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSpan reports the keys covered by the button that are already
+// covered by another one, and marks them as covered by the button.
+func (p *Page) checkSpan(grid Grid, button *Button, node *yaml.Node, covered map[int]*yaml.Node) []error {
+	if grid.Columns <= 0 || grid.Rows <= 0 {
+		return nil
+	}
+	var errs []error
+	index := int(button.Index)
+	for _, key := range grid.spanKeys(index, grid.clip(index, grid.span(index, button))) {
+		if other, exists := covered[key]; exists {
+			errs = append(errs, p.errorAt(node, "key %d is also covered by the button on line %d", key, other.Line))
+			continue
+		}
+		covered[key] = node
+	}
+	return errs
+}
+
+// checkAction checks the action at node.
+func (p *Page) checkAction(c *Checker, action Action, node *yaml.Node) []error {
+	value := field(node, "value")
+	empty := len(action.Value) == 0 || action.Value[0] == ""
+	switch action.Type {
+	case "":
+		if len(action.Value) > 0 {
+			return []error{p.errorAt(value, "action value without a type")}
+		}
+	case "exec":
+		if empty {
+			return []error{p.errorAt(value, "exec action without a command")}
+		}
+	case "set_page":
+		switch {
+		case empty:
+			return []error{p.errorAt(value, "set_page action without a page")}
+		case !c.Pages[action.Value[0]]:
+			return []error{p.errorAt(value, "set_page to unknown page %q", action.Value[0])}
+		}
+	default:
+		return []error{p.errorAt(field(node, "type"), "unknown action type %q", action.Type)}
+	}
+	return nil
+}
+
+// checkIcons looks up every icon with a file or a theme name in the node,
+// including those of icon layers. parent is the key whose value the node
+// is.
+func (p *Page) checkIcons(c *Checker, node *yaml.Node, parent string) []error {
+	var errs []error
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			errs = append(errs, p.checkIcons(c, child, parent)...)
+		}
+	case yaml.MappingNode:
+		if iconKeys[parent] {
+			var icon Icon
+			err := node.Decode(&icon)
+			if err == nil && (icon.File != "" || icon.Theme != "") {
+				err = c.FindIcon(icon)
+				if err != nil {
+					at := field(node, "file")
+					if icon.Theme != "" {
+						at = field(node, "theme")
+					}
+					errs = append(errs, p.errorAt(at, "%v", err))
+				}
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			errs = append(errs, p.checkIcons(c, node.Content[i+1], node.Content[i].Value)...)
+		}
+	}
+	return errs
+}
+
+// File returns the file the page was loaded from.
+func (p *Page) File() string {
+	return p.file
+}
+
+// ErrorAt returns an error located at the value of the top level key of
+// the page file, or at its start when the key is not set.
+func (p *Page) ErrorAt(key string, format string, args ...any) error {
+	return p.errorAt(field(p.doc, key), format, args...)
+}
+
+func (p *Page) errorAt(node *yaml.Node, format string, args ...any) error {
+	if node == nil {
+		return NodeError(p.file, &yaml.Node{Line: 1, Column: 1}, format, args...)
+	}
+	return NodeError(p.file, node, format, args...)
+}
+
+// field returns the value of the key in the mapping node, or the node
+// itself, which still locates the problem, when the key is not set.
+func field(node *yaml.Node, key string) *yaml.Node {
+	if value := LookupNode(node, key); value != nil {
+		return value
+	}
+	return node
+}
@@ -0,0 +1,58 @@
+package page
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			name: "set_page",
+			src:  "  - action: {type: set_page, value: [other]}\n",
+		},
+		{
+			name:    "set_page to an unknown page",
+			src:     "  - action: {type: set_page, value: [missing]}\n",
+			wantErr: "page.yml:3:37: set_page to unknown page \"missing\"",
+		},
+		{
+			name:    "exec without a command",
+			src:     "  - action: {type: exec}\n",
+			wantErr: "exec action without a command",
+		},
+		{
+			name:    "unknown type",
+			src:     "  - action: {type: open, value: [x]}\n",
+			wantErr: "page.yml:3:20: unknown action type \"open\"",
+		},
+		{
+			name: "clock switching pages",
+			src:  "  - clock: {type: countdown, target: \"2026-12-24\", action: {type: set_page, value: [other]}}\n",
+		},
+		{
+			name:    "clock switching to an unknown page",
+			src:     "  - clock: {type: countdown, target: \"2026-12-24\", action: {type: set_page, value: [missing]}}\n",
+			wantErr: "set_page to unknown page \"missing\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := loadPage(t, "name: actions\nbuttons:\n"+tt.src)
+			err := p.Check(&Checker{Pages: map[string]bool{"actions": true, "other": true}})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Check() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package page
+
+import "time"
+
+// Clock turns a button into a time display. Type is one of clock, date,
+// countdown, stopwatch or pomodoro.
+type Clock struct {
+	Type      string
+	Format    string
+	Target    string
+	FontSize  int    `yaml:"font_size"`
+	FontColor string `yaml:"font_color"`
+	Work      time.Duration
+	Break     time.Duration
+	LongBreak time.Duration `yaml:"long_break"`
+	Rounds    int
+	WorkFill  string `yaml:"work_fill"`
+	BreakFill string `yaml:"break_fill"`
+	Action    *Action
+}
@@ -0,0 +1,335 @@
+package page
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/colornames"
+)
+
+// Paint is a parsed fill: a solid colour or a gradient. Type is solid,
+// linear or radial.
+type Paint struct {
+	Type  string
+	Color color.RGBA
+	// Angle is the direction of a linear gradient in degrees, as in CSS: 0
+	// going from bottom to top, 90 from left to right and 180, the default,
+	// from top to bottom.
+	Angle float64
+	Stops []ColorStop
+}
+
+// ColorStop is a colour of a gradient at an offset from 0 to 1 along it.
+type ColorStop struct {
+	Color  color.RGBA
+	Offset float64
+}
+
+var errGradientColor = errors.New("gradients are only allowed in fills")
+
+// ParseColor reads a colour:
+//
+//   - hex: #RGB, #RGBA, #RRGGBB or #RRGGBBAA
+//   - CSS names like "tomato" or "transparent"
+//   - rgb(255, 99, 71), rgba(255, 99, 71, 0.5), rgb(100% 39% 28% / 50%)
+//   - hsl(9, 100%, 64%) and hsla(9, 100%, 64%, 0.5)
+//
+// The alpha of the returned colour is not premultiplied.
+func ParseColor(s string) (color.RGBA, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	name, args, isFunc := splitFunc(value)
+	switch {
+	case value == "":
+		return color.RGBA{A: 255}, errors.New("empty color")
+	case value == "transparent":
+		return color.RGBA{}, nil
+	case strings.HasPrefix(value, "#"):
+		return parseHex(value[1:], s)
+	case isFunc && (name == "rgb" || name == "rgba"):
+		return parseRGB(args, s)
+	case isFunc && (name == "hsl" || name == "hsla"):
+		return parseHSL(args, s)
+	case isFunc && isGradient(name):
+		return color.RGBA{A: 255}, errGradientColor
+	}
+	if named, exists := colornames.Map[value]; exists {
+		return named, nil
+	}
+	// Hex colours without the hash are accepted for older configurations.
+	if col, err := parseHex(value, s); err == nil && (len(value) == 6 || len(value) == 8) {
+		return col, nil
+	}
+	return color.RGBA{A: 255}, fmt.Errorf("unknown color: %s", s)
+}
+
+// ParsePaint reads a fill: any colour ParseColor accepts, or a gradient
+// with two or more colour stops, each optionally followed by its offset:
+//
+//   - linear(90deg, #ff0000, #0000ff) or linear(to right, red, blue 80%)
+//   - radial(white, black), from the centre to the farthest corner
+func ParsePaint(s string) (Paint, error) {
+	name, args, isFunc := splitFunc(strings.ToLower(strings.TrimSpace(s)))
+	if !isFunc || !isGradient(name) {
+		col, err := ParseColor(s)
+		return Paint{Type: "solid", Color: col}, err
+	}
+	paint := Paint{Type: strings.TrimSuffix(name, "-gradient")}
+	parts := splitArgs(args, ',')
+	if paint.Type == "linear" {
+		paint.Angle = 180
+	}
+	if paint.Type == "linear" && len(parts) > 0 {
+		angle, isAngle, err := parseDirection(parts[0])
+		if err != nil {
+			return paint, err
+		}
+		if isAngle {
+			paint.Angle = angle
+			parts = parts[1:]
+		}
+	}
+	if len(parts) < 2 {
+		return paint, fmt.Errorf("gradient needs at least two colors: %s", s)
+	}
+	stops, err := parseStops(parts)
+	if err != nil {
+		return paint, err
+	}
+	paint.Stops = stops
+	return paint, nil
+}
+
+func isGradient(name string) bool {
+	switch name {
+	case "linear", "radial", "linear-gradient", "radial-gradient":
+		return true
+	}
+	return false
+}
+
+// splitFunc splits "name(args)" into its name and arguments.
+func splitFunc(s string) (name, args string, ok bool) {
+	open := strings.IndexByte(s, '(')
+	if open <= 0 || !strings.HasSuffix(s, ")") {
+		return "", "", false
+	}
+	return strings.TrimSpace(s[:open]), s[open+1 : len(s)-1], true
+}
+
+// splitArgs splits at sep outside parentheses and trims the parts.
+func splitArgs(s string, sep rune) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == sep && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+func parseHex(hex string, original string) (color.RGBA, error) {
+	invalid := fmt.Errorf("invalid hex color: %s", original)
+	switch len(hex) {
+	case 3, 4:
+		long := make([]byte, 0, 2*len(hex))
+		for i := range len(hex) {
+			long = append(long, hex[i], hex[i])
+		}
+		hex = string(long)
+	case 6, 8:
+	default:
+		return color.RGBA{A: 255}, invalid
+	}
+	channels := []uint8{0, 0, 0, 255}
+	for i := 0; i < len(hex); i += 2 {
+		v, err := strconv.ParseUint(hex[i:i+2], 16, 8)
+		if err != nil {
+			return color.RGBA{A: 255}, invalid
+		}
+		channels[i/2] = uint8(v)
+	}
+	return color.RGBA{R: channels[0], G: channels[1], B: channels[2], A: channels[3]}, nil
+}
+
+// colorArgs splits the arguments of rgb() and hsl(), separated by commas or
+// by spaces with the alpha after a slash.
+func colorArgs(args string) []string {
+	if strings.Contains(args, ",") {
+		return splitArgs(args, ',')
+	}
+	return strings.Fields(strings.Replace(args, "/", " ", 1))
+}
+
+func parseRGB(args string, original string) (color.RGBA, error) {
+	parts := colorArgs(args)
+	if len(parts) != 3 && len(parts) != 4 {
+		return color.RGBA{A: 255}, fmt.Errorf("rgb needs 3 or 4 values: %s", original)
+	}
+	var channels [3]uint8
+	for i, part := range parts[:3] {
+		v, err := parseNumber(part, 255)
+		if err != nil || v < 0 || v > 255 {
+			return color.RGBA{A: 255}, fmt.Errorf("invalid value %q in %s", part, original)
+		}
+		channels[i] = uint8(math.Round(v))
+	}
+	alpha, err := parseAlpha(parts, original)
+	if err != nil {
+		return color.RGBA{A: 255}, err
+	}
+	return color.RGBA{R: channels[0], G: channels[1], B: channels[2], A: alpha}, nil
+}
+
+func parseHSL(args string, original string) (color.RGBA, error) {
+	parts := colorArgs(args)
+	if len(parts) != 3 && len(parts) != 4 {
+		return color.RGBA{A: 255}, fmt.Errorf("hsl needs 3 or 4 values: %s", original)
+	}
+	hue, err := strconv.ParseFloat(strings.TrimSuffix(parts[0], "deg"), 64)
+	if err != nil {
+		return color.RGBA{A: 255}, fmt.Errorf("invalid hue %q in %s", parts[0], original)
+	}
+	var sl [2]float64
+	for i, part := range parts[1:3] {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(part, "%"), 64)
+		if err != nil || v < 0 || v > 100 {
+			return color.RGBA{A: 255}, fmt.Errorf("invalid value %q in %s", part, original)
+		}
+		sl[i] = v / 100
+	}
+	alpha, err := parseAlpha(parts, original)
+	if err != nil {
+		return color.RGBA{A: 255}, err
+	}
+	r, g, b := hslToRGB(math.Mod(math.Mod(hue, 360)+360, 360), sl[0], sl[1])
+	return color.RGBA{R: r, G: g, B: b, A: alpha}, nil
+}
+
+// parseAlpha reads the optional fourth value, from 0 to 1 or a percentage.
+func parseAlpha(parts []string, original string) (uint8, error) {
+	if len(parts) < 4 {
+		return 255, nil
+	}
+	v, err := parseNumber(parts[3], 1)
+	if err != nil || v < 0 || v > 1 {
+		return 0, fmt.Errorf("invalid alpha %q in %s", parts[3], original)
+	}
+	return uint8(math.Round(v * 255)), nil
+}
+
+// parseNumber reads a number, or a percentage of full.
+func parseNumber(s string, full float64) (float64, error) {
+	if percent, ok := strings.CutSuffix(s, "%"); ok {
+		v, err := strconv.ParseFloat(percent, 64)
+		return v / 100 * full, err
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func hslToRGB(h, s, l float64) (uint8, uint8, uint8) {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	channel := func(v float64) uint8 {
+		return uint8(math.Round((v + m) * 255))
+	}
+	return channel(r), channel(g), channel(b)
+}
+
+// parseDirection reads the optional first argument of a linear gradient:
+// an angle like "90deg" or a side like "to right".
+func parseDirection(s string) (angle float64, ok bool, err error) {
+	if side, found := strings.CutPrefix(s, "to "); found {
+		switch strings.TrimSpace(side) {
+		case "top":
+			return 0, true, nil
+		case "right":
+			return 90, true, nil
+		case "bottom":
+			return 180, true, nil
+		case "left":
+			return 270, true, nil
+		}
+		return 0, false, fmt.Errorf("unknown gradient direction: %s", s)
+	}
+	if degrees, found := strings.CutSuffix(s, "deg"); found {
+		angle, err := strconv.ParseFloat(degrees, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid gradient angle: %s", s)
+		}
+		return angle, true, nil
+	}
+	return 0, false, nil
+}
+
+// parseStops reads the colour stops of a gradient. Stops without an offset
+// are spread evenly between their neighbours, the first at 0 and the last
+// at 1 by default.
+func parseStops(parts []string) ([]ColorStop, error) {
+	stops := make([]ColorStop, len(parts))
+	known := make([]bool, len(parts))
+	for i, part := range parts {
+		colorPart := part
+		space := strings.LastIndexByte(part, ' ')
+		if strings.HasSuffix(part, "%") && space > strings.LastIndexByte(part, ')') {
+			offset, err := strconv.ParseFloat(strings.TrimSuffix(part[space+1:], "%"), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid color stop: %s", part)
+			}
+			stops[i].Offset = offset / 100
+			known[i] = true
+			colorPart = strings.TrimSpace(part[:space])
+		}
+		col, err := ParseColor(colorPart)
+		if err != nil {
+			return nil, err
+		}
+		stops[i].Color = col
+	}
+	last := len(stops) - 1
+	if !known[0] {
+		stops[0].Offset, known[0] = 0, true
+	}
+	if !known[last] {
+		stops[last].Offset, known[last] = 1, true
+	}
+	prev := 0
+	for i := 1; i <= last; i++ {
+		if !known[i] {
+			continue
+		}
+		for j := prev + 1; j < i; j++ {
+			t := float64(j-prev) / float64(i-prev)
+			stops[j].Offset = stops[prev].Offset + (stops[i].Offset-stops[prev].Offset)*t
+		}
+		stops[i].Offset = max(stops[i].Offset, stops[prev].Offset)
+		prev = i
+	}
+	return stops, nil
+}
@@ -0,0 +1,191 @@
+package page
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    color.RGBA
+		wantErr bool
+	}{
+		{in: "#fff", want: color.RGBA{R: 255, G: 255, B: 255, A: 255}},
+		{in: "#f008", want: color.RGBA{R: 255, A: 136}},
+		{in: "#FF6347", want: color.RGBA{R: 255, G: 99, B: 71, A: 255}},
+		{in: "#ff634780", want: color.RGBA{R: 255, G: 99, B: 71, A: 128}},
+		{in: "ff6347", want: color.RGBA{R: 255, G: 99, B: 71, A: 255}},
+		{in: "tomato", want: color.RGBA{R: 255, G: 99, B: 71, A: 255}},
+		{in: "transparent", want: color.RGBA{}},
+		{in: "rgb(255, 99, 71)", want: color.RGBA{R: 255, G: 99, B: 71, A: 255}},
+		{in: "rgba(255, 99, 71, 0.5)", want: color.RGBA{R: 255, G: 99, B: 71, A: 128}},
+		{in: "rgb(100% 0% 0% / 50%)", want: color.RGBA{R: 255, A: 128}},
+		{in: "hsl(0, 100%, 50%)", want: color.RGBA{R: 255, A: 255}},
+		{in: "hsl(120, 100%, 50%)", want: color.RGBA{G: 255, A: 255}},
+		{in: "hsl(360, 100%, 50%)", want: color.RGBA{R: 255, A: 255}},
+		{in: "hsl(480deg, 100%, 50%)", want: color.RGBA{G: 255, A: 255}},
+		{in: "hsl(-120, 100%, 50%)", want: color.RGBA{B: 255, A: 255}},
+		{in: "hsla(240, 100%, 50%, 0)", want: color.RGBA{B: 255}},
+
+		{in: "", wantErr: true},
+		{in: "#", wantErr: true},
+		{in: "#12", wantErr: true},
+		{in: "#12345", wantErr: true},
+		{in: "#1234567", wantErr: true},
+		{in: "#ggg", wantErr: true},
+		{in: "#12345z", wantErr: true},
+		{in: "12345", wantErr: true},
+		{in: "not-a-color", wantErr: true},
+		{in: "rgb(256, 0, 0)", wantErr: true},
+		{in: "rgb(0, 0)", wantErr: true},
+		{in: "rgba(0, 0, 0, 2)", wantErr: true},
+		{in: "hsl(0, 101%, 50%)", wantErr: true},
+		{in: "hsl(red, 100%, 50%)", wantErr: true},
+		{in: "linear(red, blue)", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseColor(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseColor(%q) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseColor(%q) error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseColor(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePaint(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	green := color.RGBA{G: 128, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	tests := []struct {
+		in      string
+		want    Paint
+		wantErr bool
+	}{
+		{
+			in:   "red",
+			want: Paint{Type: "solid", Color: red},
+		},
+		{
+			in: "linear(red, blue)",
+			want: Paint{Type: "linear", Angle: 180, Stops: []ColorStop{
+				{Color: red, Offset: 0}, {Color: blue, Offset: 1},
+			}},
+		},
+		{
+			in: "linear(90deg, red, blue)",
+			want: Paint{Type: "linear", Angle: 90, Stops: []ColorStop{
+				{Color: red, Offset: 0}, {Color: blue, Offset: 1},
+			}},
+		},
+		{
+			in: "linear-gradient(to left, red, blue)",
+			want: Paint{Type: "linear", Angle: 270, Stops: []ColorStop{
+				{Color: red, Offset: 0}, {Color: blue, Offset: 1},
+			}},
+		},
+		{
+			in: "radial(red, green, blue)",
+			want: Paint{Type: "radial", Stops: []ColorStop{
+				{Color: red, Offset: 0}, {Color: green, Offset: 0.5}, {Color: blue, Offset: 1},
+			}},
+		},
+		{
+			// Stops without an offset are spread between the known ones.
+			in: "linear(red 20%, green, green, blue 80%)",
+			want: Paint{Type: "linear", Angle: 180, Stops: []ColorStop{
+				{Color: red, Offset: 0.2}, {Color: green, Offset: 0.4}, {Color: green, Offset: 0.6}, {Color: blue, Offset: 0.8},
+			}},
+		},
+		{
+			in: "linear(red, green 10%, blue)",
+			want: Paint{Type: "linear", Angle: 180, Stops: []ColorStop{
+				{Color: red, Offset: 0}, {Color: green, Offset: 0.1}, {Color: blue, Offset: 1},
+			}},
+		},
+		{
+			// An offset before the previous one is moved up to it.
+			in: "linear(red 60%, blue 30%)",
+			want: Paint{Type: "linear", Angle: 180, Stops: []ColorStop{
+				{Color: red, Offset: 0.6}, {Color: blue, Offset: 0.6},
+			}},
+		},
+		{
+			in: "linear(rgb(255, 0, 0) 0%, rgba(0, 0, 255, 1) 100%)",
+			want: Paint{Type: "linear", Angle: 180, Stops: []ColorStop{
+				{Color: red, Offset: 0}, {Color: blue, Offset: 1},
+			}},
+		},
+
+		{in: "linear(red)", wantErr: true},
+		{in: "linear(90deg, red)", wantErr: true},
+		{in: "linear(red, blue x%)", wantErr: true},
+		{in: "radial(red, #12345)", wantErr: true},
+		{in: "linear(sideways, red, blue)", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePaint(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParsePaint(%q) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePaint(%q) error: %v", tt.in, err)
+			}
+			if got.Type != tt.want.Type || got.Color != tt.want.Color || got.Angle != tt.want.Angle {
+				t.Errorf("ParsePaint(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+			if len(got.Stops) != len(tt.want.Stops) {
+				t.Fatalf("ParsePaint(%q) stops = %v, want %v", tt.in, got.Stops, tt.want.Stops)
+			}
+			for i, stop := range got.Stops {
+				want := tt.want.Stops[i]
+				if stop.Color != want.Color || math.Abs(stop.Offset-want.Offset) > 1e-9 {
+					t.Errorf("ParsePaint(%q) stop %d = %v, want %v", tt.in, i, stop, want)
+				}
+			}
+		})
+	}
+}
+
+// The angles follow CSS: 0deg runs from the bottom up, like "to top", and
+// 90deg from left to right, like "to right".
+func TestParseDirection(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   float64
+		wantOK bool
+	}{
+		{in: "0deg", want: 0, wantOK: true},
+		{in: "to top", want: 0, wantOK: true},
+		{in: "90deg", want: 90, wantOK: true},
+		{in: "to right", want: 90, wantOK: true},
+		{in: "to bottom", want: 180, wantOK: true},
+		{in: "to left", want: 270, wantOK: true},
+		{in: "-45deg", want: -45, wantOK: true},
+		{in: "red"},
+	}
+	for _, tt := range tests {
+		got, ok, err := parseDirection(tt.in)
+		if err != nil {
+			t.Fatalf("parseDirection(%q) error: %v", tt.in, err)
+		}
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("parseDirection(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
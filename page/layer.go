@@ -0,0 +1,32 @@
+package page
+
+// Filter is one step of the filter pipeline of an icon. Each entry of the
+// list normally sets a single filter, for example {grayscale: 1} or
+// {blur: 2}, and the entries are applied in order.
+type Filter struct {
+	// Grayscale removes colour, from 0 (none) to 1 (fully grey).
+	Grayscale float64
+	// Brightness is added to every channel, from -1 (black) to 1 (white).
+	Brightness float64
+	// Contrast scales the distance from mid grey, from -1 (flat grey)
+	// upwards, 0 leaving the image as it is.
+	Contrast float64
+	// Tint multiplies the colours with a colour, keeping the shading. The
+	// alpha of the colour is the strength.
+	Tint string
+	// Blur is the blur radius in pixels.
+	Blur   int
+	Invert bool
+	// Opacity multiplies the alpha, from 0 (invisible) to 1. It is a pointer
+	// because 0 is a valid opacity.
+	Opacity *float64
+}
+
+// Layer is one layer of an icon, drawn over the icon image and the layers
+// before it: a fill, which may be a gradient like every fill, another icon
+// or a text.
+type Layer struct {
+	Fill string
+	Icon *Icon
+	Text *Label
+}
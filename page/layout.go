@@ -0,0 +1,180 @@
+package page
+
+import (
+	"errors"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Pos places a button by row and column instead of by index, so a page
+// works on decks of other sizes. Negative values count from the bottom row
+// and the right column, -1 being the last.
+type Pos struct {
+	Row int
+	Col int
+}
+
+// Grid is the key layout of a device. Keys may be fewer than the columns
+// times the rows.
+type Grid struct {
+	Columns int
+	Rows    int
+	Keys    int
+}
+
+// index returns the key at the row and column, which may count from the
+// end, and whether it is on the grid. On a bottom row with fewer keys the
+// columns count from its last key.
+func (g Grid) index(row, col int) (int, bool) {
+	if row < 0 {
+		row += g.Rows
+	}
+	if row < 0 || row >= g.Rows {
+		return 0, false
+	}
+	columns := min(g.Columns, g.Keys-row*g.Columns)
+	if col < 0 {
+		col += columns
+	}
+	if col < 0 || col >= columns {
+		return 0, false
+	}
+	return row*g.Columns + col, true
+}
+
+// Place sets the index of the buttons placed with pos. With layout auto
+// the buttons without an index or a pos flow, in order, into the keys left
+// free by the others. Flowing buttons without a free key, and buttons with
+// an index past the keys of the device, are left for Paginate. Placing the
+// page again places its buttons anew.
+func (p *Page) Place(grid Grid) error {
+	if p.doc == nil {
+		return nil
+	}
+	var errs []error
+	switch p.Layout {
+	case "", "auto":
+	default:
+		errs = append(errs, p.errorAt(field(p.doc, "layout"), "unknown layout %q", p.Layout))
+	}
+	nodes := p.buttonNodes()
+	var flowing []int
+	for i := range p.Buttons {
+		if i >= len(nodes) {
+			break
+		}
+		button, node := &p.Buttons[i], nodes[i]
+		button.flows, button.overflow = false, false
+		index := LookupNode(node, "index")
+		switch {
+		case button.Pos != nil && index != nil:
+			errs = append(errs, p.errorAt(index, "index and pos are both set"))
+		case button.Pos != nil:
+			key, ok := grid.index(button.Pos.Row, button.Pos.Col)
+			if !ok {
+				errs = append(errs, p.errorAt(field(node, "pos"), "row %d column %d is outside the %dx%d keys of the device",
+					button.Pos.Row, button.Pos.Col, grid.Columns, grid.Rows))
+				continue
+			}
+			button.Index = uint8(key)
+		case index == nil && p.Layout == "auto":
+			flowing = append(flowing, i)
+		case int(button.Index) >= grid.Keys:
+			button.overflow = true
+		}
+	}
+	if len(flowing) == 0 {
+		return errors.Join(errs...)
+	}
+
+	var placed []*Button
+	for i := range p.Buttons {
+		if !slices.Contains(flowing, i) && !p.Buttons[i].overflow {
+			placed = append(placed, &p.Buttons[i])
+		}
+	}
+	used := grid.usedKeys(placed)
+	key := 0
+	for _, i := range flowing {
+		button := &p.Buttons[i]
+		for key < grid.Keys && !grid.fits(used, key, button) {
+			key++
+		}
+		if key >= grid.Keys {
+			button.overflow = true
+			continue
+		}
+		button.Index = uint8(key)
+		button.flows = true
+		for _, k := range grid.spanKeys(key, grid.span(key, button)) {
+			used[k] = true
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// span returns the columns and rows the button covers from the key. Chart
+// widgets are Span keys wide, cut at the end of the row.
+func (g Grid) span(key int, b *Button) Span {
+	if b.Widget != nil {
+		return g.clip(key, Span{Cols: b.Widget.Columns(), Rows: 1})
+	}
+	if b.Span == nil {
+		return Span{Cols: 1, Rows: 1}
+	}
+	return Span{Cols: max(b.Span.Cols, 1), Rows: max(b.Span.Rows, 1)}
+}
+
+// fits reports whether the button placed at the key is on the grid and
+// covers no used key.
+func (g Grid) fits(used map[int]bool, key int, b *Button) bool {
+	span := g.span(key, b)
+	col, row := key%g.Columns, key/g.Columns
+	if col+span.Cols > g.Columns || row+span.Rows > g.Rows {
+		return false
+	}
+	for _, k := range g.spanKeys(key, span) {
+		if used[k] || k >= g.Keys {
+			return false
+		}
+	}
+	return true
+}
+
+// usedKeys returns the keys covered by the buttons.
+func (g Grid) usedKeys(buttons []*Button) map[int]bool {
+	used := make(map[int]bool)
+	for _, button := range buttons {
+		index := int(button.Index)
+		for _, key := range g.spanKeys(index, g.clip(index, g.span(index, button))) {
+			used[key] = true
+		}
+	}
+	return used
+}
+
+// clip returns the span starting at the key cut at the edges of the grid.
+func (g Grid) clip(key int, span Span) Span {
+	col, row := key%g.Columns, key/g.Columns
+	return Span{Cols: min(span.Cols, g.Columns-col), Rows: min(span.Rows, g.Rows-row)}
+}
+
+// spanKeys returns the keys covered by the span starting at the key.
+func (g Grid) spanKeys(key int, span Span) []int {
+	var keys []int
+	for row := 0; row < span.Rows; row++ {
+		for col := 0; col < span.Cols; col++ {
+			keys = append(keys, key+row*g.Columns+col)
+		}
+	}
+	return keys
+}
+
+// buttonNodes returns the YAML nodes of the buttons, in order.
+func (p *Page) buttonNodes() []*yaml.Node {
+	if buttons := LookupNode(p.doc, "buttons"); buttons != nil {
+		return buttons.Content
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+package page
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+var (
+	originalGrid = Grid{Columns: 5, Rows: 3, Keys: 15}
+	miniGrid     = Grid{Columns: 3, Rows: 2, Keys: 6}
+	// The bottom row of the Neo has two keys only.
+	neoGrid = Grid{Columns: 4, Rows: 3, Keys: 10}
+)
+
+// loadPage loads the page from YAML written to a temporary file.
+func loadPage(t *testing.T, src string) *Page {
+	t.Helper()
+	p := NewPage()
+	err := p.LoadPage(writePage(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func indices(buttons []Button) []int {
+	var keys []int
+	for _, button := range buttons {
+		keys = append(keys, int(button.Index))
+	}
+	return keys
+}
+
+func TestGridIndex(t *testing.T) {
+	tests := []struct {
+		row, col int
+		want     int
+		wantOK   bool
+	}{
+		{row: 0, col: 0, want: 0, wantOK: true},
+		{row: 1, col: 2, want: 7, wantOK: true},
+		{row: 2, col: 4, want: 14, wantOK: true},
+		{row: -1, col: -1, want: 14, wantOK: true},
+		{row: -1, col: 0, want: 10, wantOK: true},
+		{row: 0, col: -5, want: 0, wantOK: true},
+		{row: -3, col: -5, want: 0, wantOK: true},
+		{row: 3, col: 0},
+		{row: 0, col: 5},
+		{row: -4, col: 0},
+		{row: 0, col: -6},
+	}
+	for _, tt := range tests {
+		got, ok := originalGrid.index(tt.row, tt.col)
+		if ok != tt.wantOK || (ok && got != tt.want) {
+			t.Errorf("index(%d, %d) = %d, %v, want %d, %v", tt.row, tt.col, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	// Columns count from the last key of a partial bottom row.
+	neo := []struct {
+		row, col int
+		want     int
+		wantOK   bool
+	}{
+		{row: -1, col: -1, want: 9, wantOK: true},
+		{row: -1, col: -2, want: 8, wantOK: true},
+		{row: 1, col: -1, want: 7, wantOK: true},
+		{row: -1, col: 2},
+		{row: -1, col: -3},
+	}
+	for _, tt := range neo {
+		got, ok := neoGrid.index(tt.row, tt.col)
+		if ok != tt.wantOK || (ok && got != tt.want) {
+			t.Errorf("neo index(%d, %d) = %d, %v, want %d, %v", tt.row, tt.col, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestGridSpan(t *testing.T) {
+	chart := func(span int) *Button {
+		return &Button{Widget: &Widget{Display: "chart", Span: span}}
+	}
+	tests := []struct {
+		name   string
+		grid   Grid
+		key    int
+		button *Button
+		want   Span
+	}{
+		{name: "plain button", grid: originalGrid, key: 0, button: &Button{}, want: Span{Cols: 1, Rows: 1}},
+		{name: "span", grid: originalGrid, key: 0, button: &Button{Span: &Span{Cols: 2, Rows: 2}}, want: Span{Cols: 2, Rows: 2}},
+		{name: "empty span", grid: originalGrid, key: 0, button: &Button{Span: &Span{}}, want: Span{Cols: 1, Rows: 1}},
+		// Spans are not cut, so buttons that do not fit flow elsewhere.
+		{name: "span at the right edge", grid: originalGrid, key: 4, button: &Button{Span: &Span{Cols: 2, Rows: 1}}, want: Span{Cols: 2, Rows: 1}},
+		{name: "meter widget", grid: originalGrid, key: 0, button: &Button{Widget: &Widget{Display: "bar", Span: 3}}, want: Span{Cols: 1, Rows: 1}},
+		{name: "chart", grid: originalGrid, key: 0, button: chart(3), want: Span{Cols: 3, Rows: 1}},
+		{name: "chart without span", grid: originalGrid, key: 0, button: chart(0), want: Span{Cols: 1, Rows: 1}},
+		{name: "chart at the right edge", grid: originalGrid, key: 3, button: chart(3), want: Span{Cols: 2, Rows: 1}},
+		{name: "chart in the last column", grid: originalGrid, key: 14, button: chart(3), want: Span{Cols: 1, Rows: 1}},
+		{name: "chart on the mini", grid: miniGrid, key: 3, button: chart(5), want: Span{Cols: 3, Rows: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.grid.span(tt.key, tt.button); got != tt.want {
+				t.Errorf("span(%d) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGridFits(t *testing.T) {
+	square := &Button{Span: &Span{Cols: 2, Rows: 2}}
+	tests := []struct {
+		name   string
+		grid   Grid
+		used   []int
+		key    int
+		button *Button
+		want   bool
+	}{
+		{name: "free key", grid: originalGrid, key: 0, button: &Button{}, want: true},
+		{name: "used key", grid: originalGrid, used: []int{0}, key: 0, button: &Button{}},
+		{name: "square", grid: originalGrid, key: 3, button: square, want: true},
+		{name: "square over a used key", grid: originalGrid, used: []int{9}, key: 3, button: square},
+		{name: "square past the right edge", grid: originalGrid, key: 4, button: square},
+		{name: "square past the bottom edge", grid: originalGrid, key: 10, button: square},
+		{name: "square in the bottom right corner", grid: originalGrid, key: 8, button: square, want: true},
+		{name: "key past the partial row", grid: neoGrid, key: 10, button: &Button{}},
+		{name: "square over the partial row", grid: neoGrid, key: 5, button: square},
+		{name: "square on the keys of the partial row", grid: neoGrid, key: 4, button: square, want: true},
+		{name: "square at the start of the partial row", grid: neoGrid, key: 4, button: &Button{Span: &Span{Cols: 2, Rows: 1}}, want: true},
+		{name: "chart cut at the edge", grid: originalGrid, key: 4, button: &Button{Widget: &Widget{Display: "chart", Span: 3}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used := make(map[int]bool)
+			for _, key := range tt.used {
+				used[key] = true
+			}
+			if got := tt.grid.fits(used, tt.key, tt.button); got != tt.want {
+				t.Errorf("fits(%d) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlace(t *testing.T) {
+	tests := []struct {
+		name     string
+		grid     Grid
+		src      string
+		want     []int
+		overflow []int
+		wantErr  string
+	}{
+		{
+			name: "index",
+			grid: originalGrid,
+			src: `
+buttons:
+  - index: 3
+  - index: 0
+`,
+			want: []int{3, 0},
+		},
+		{
+			name: "pos",
+			grid: originalGrid,
+			src: `
+buttons:
+  - pos: {row: 1, col: 2}
+  - pos: {row: -1, col: -1}
+  - pos: {row: -3, col: 0}
+`,
+			want: []int{7, 14, 0},
+		},
+		{
+			name: "buttons without an index do not flow without layout auto",
+			grid: originalGrid,
+			src: `
+buttons:
+  - index: 2
+  - label: {text: a}
+`,
+			want: []int{2, 0},
+		},
+		{
+			name: "auto flow around placed buttons",
+			grid: originalGrid,
+			src: `
+layout: auto
+buttons:
+  - label: {text: a}
+  - index: 0
+  - label: {text: b}
+  - pos: {row: 0, col: 2}
+  - label: {text: c}
+`,
+			want: []int{1, 0, 3, 2, 4},
+		},
+		{
+			name: "auto flow keeps the order after a span",
+			grid: originalGrid,
+			src: `
+layout: auto
+buttons:
+  - index: 1
+  - span: {cols: 2, rows: 2}
+  - label: {text: a}
+`,
+			want: []int{1, 2, 4},
+		},
+		{
+			name: "auto flow of a chart",
+			grid: originalGrid,
+			src: `
+layout: auto
+buttons:
+  - index: 0
+  - widget: {type: cpu, display: chart, span: 3}
+  - label: {text: a}
+`,
+			want: []int{0, 1, 4},
+		},
+		{
+			name: "auto flow beyond the keys",
+			grid: miniGrid,
+			src: `
+layout: auto
+buttons:
+  - pos: {row: -1, col: -1}
+  - label: {text: a}
+  - label: {text: b}
+  - label: {text: c}
+  - label: {text: d}
+  - label: {text: e}
+  - label: {text: f}
+  - label: {text: g}
+`,
+			want:     []int{5, 0, 1, 2, 3, 4, 0, 0},
+			overflow: []int{6, 7},
+		},
+		{
+			name: "pos outside the grid",
+			grid: originalGrid,
+			src: `
+buttons:
+  - pos: {row: 3, col: 0}
+`,
+			wantErr: "page.yml:3:10: row 3 column 0 is outside the 5x3 keys of the device",
+		},
+		{
+			name: "negative pos outside the grid",
+			grid: miniGrid,
+			src: `
+buttons:
+  - pos: {row: 0, col: -4}
+`,
+			wantErr: "row 0 column -4 is outside the 3x2 keys of the device",
+		},
+		{
+			name: "index and pos",
+			grid: originalGrid,
+			src: `
+buttons:
+  - index: 1
+    pos: {row: 0, col: 0}
+`,
+			wantErr: "page.yml:3:12: index and pos are both set",
+		},
+		{
+			name: "unknown layout",
+			grid: originalGrid,
+			src: `
+layout: grid
+buttons: []
+`,
+			wantErr: "page.yml:2:9: unknown layout \"grid\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := loadPage(t, tt.src)
+			err := p.Place(tt.grid)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Place() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Place() error: %v", err)
+			}
+			if got := indices(p.Buttons); !slices.Equal(got, tt.want) {
+				t.Errorf("Place() indices = %v, want %v", got, tt.want)
+			}
+			var overflow []int
+			for i, button := range p.Buttons {
+				if button.overflow {
+					overflow = append(overflow, i)
+				}
+			}
+			if !slices.Equal(overflow, tt.overflow) {
+				t.Errorf("Place() overflow = %v, want %v", overflow, tt.overflow)
+			}
+		})
+	}
+}
@@ -2,33 +2,146 @@ package page
 
 import (
 	"fmt"
-	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Page struct {
-	Name    string
-	Buttons []Button
+	Name       string
+	Theme      Theme
+	NightTheme Theme `yaml:"night_theme"`
+	Layout     string
+	Background *Icon
+	Buttons    []Button
+	file       string
+	doc        *yaml.Node
+}
+
+// Span is the number of columns and rows of keys a button covers, from its
+// index to the right and down.
+type Span struct {
+	Cols int
+	Rows int
 }
 
 type Button struct {
-	Index      uint8
-	Icon       Icon
-	Label      Label
-	FontSize   int `yaml:"font_size"`
-	Action     Action
+	Index         uint8
+	Pos           *Pos
+	Icon          Icon
+	Label         Label
+	Subtitle      *Label
+	Badge         *Badge
+	Span          *Span
+	Style         string
+	FontSize      int `yaml:"font_size"`
+	Action        Action
+	Widget        *Widget
+	Clock         *Clock
+	States        []State
+	StateCommand  []string      `yaml:"state_command"`
+	StateRefresh  time.Duration `yaml:"state_refresh"`
+	PressedIcon   *Icon         `yaml:"pressed_icon"`
+	PressedFill   string        `yaml:"pressed_fill"`
+	PressedEffect string        `yaml:"pressed_effect"`
+	unstyled      *Button
+	flows         bool
+	overflow      bool
 }
 
 type Icon struct {
-	File string
-	Fill string
+	File    string
+	Theme   string
+	Fill    string
+	Scale   string
+	Padding int
+	Radius  int
+	Tint    string
+	Filters []Filter
+	Layers  []Layer
 }
 
 type Label struct {
-	Text      string
-	FontSize  int    `yaml:"font_size"`
-	FontColor string `yaml:"font_color"`
+	Text          string
+	Font          string
+	FallbackFonts []string `yaml:"fallback_fonts"`
+	FontSize      int      `yaml:"font_size"`
+	FontColor     string   `yaml:"font_color"`
+	Align         string
+	Wrap          bool
+	AutoFit       bool   `yaml:"auto_fit"`
+	OutlineColor  string `yaml:"outline_color"`
+	OutlineWidth  int    `yaml:"outline_width"`
+	Shadow        bool
+	Background    string
+	Marquee       bool
+	MarqueeSpeed  int `yaml:"marquee_speed"`
+}
+
+const defaultMarqueeSpeed = 30
+
+// ScrollSpeed returns the marquee speed in pixels per second.
+func (l *Label) ScrollSpeed() int {
+	if l.MarqueeSpeed <= 0 {
+		return defaultMarqueeSpeed
+	}
+	return l.MarqueeSpeed
+}
+
+// HasPressedLook reports whether the button changes its image while pressed.
+func (b *Button) HasPressedLook() bool {
+	return b.PressedIcon != nil || b.PressedFill != "" || b.PressedEffect != ""
+}
+
+// HasMarquee reports whether the title or subtitle of the button, in any
+// of its states, is a marquee.
+func (b *Button) HasMarquee() bool {
+	if b.Label.Marquee || (b.Subtitle != nil && b.Subtitle.Marquee) {
+		return true
+	}
+	for _, state := range b.States {
+		if state.Label.Marquee {
+			return true
+		}
+	}
+	return false
+}
+
+// TextLabels returns the title and the subtitle of the button. The button
+// font size applies to slots without their own, and the subtitle is drawn
+// at the bottom unless it is aligned otherwise.
+func (b *Button) TextLabels(title Label) []Label {
+	labels := []Label{title}
+	if b.Subtitle != nil {
+		subtitle := *b.Subtitle
+		if subtitle.Align == "" {
+			subtitle.Align = "bottom center"
+		}
+		labels = append(labels, subtitle)
+	}
+	for i := range labels {
+		if labels[i].FontSize == 0 {
+			labels[i].FontSize = b.FontSize
+		}
+	}
+	return labels
+}
+
+// ValueLabel returns the label of the value a widget shows, centred on the
+// key in the font, colours and outline of the title and the button font
+// size.
+func (b *Button) ValueLabel(text string) Label {
+	return Label{
+		Text:          text,
+		Align:         "center",
+		FontSize:      b.FontSize,
+		Font:          b.Label.Font,
+		FallbackFonts: b.Label.FallbackFonts,
+		FontColor:     b.Label.FontColor,
+		OutlineColor:  b.Label.OutlineColor,
+		OutlineWidth:  b.Label.OutlineWidth,
+		Shadow:        b.Label.Shadow,
+	}
 }
 
 func NewPage() *Page {
@@ -37,16 +150,21 @@ func NewPage() *Page {
 	}
 }
 
+// LoadPage reads the page from its file. Like with DecodeFile, problems
+// like unknown keys are returned with a loaded page, which Loaded reports.
 func (p *Page) LoadPage(pageFile string) error {
-	data, err := os.ReadFile(pageFile)
-	if err != nil {
-		return err
-	}
-	err = yaml.Unmarshal(data, p)
-	if err != nil {
+	doc, err := DecodeFile(pageFile, p)
+	if doc == nil {
 		return err
 	}
+	p.file, p.doc = pageFile, doc
 
 	fmt.Printf("Loaded page: %s with %d buttons\n", p.Name, len(p.Buttons))
-	return nil
+	return err
+}
+
+// Loaded reports whether the page was read from its file, maybe with
+// problems.
+func (p *Page) Loaded() bool {
+	return p.doc != nil
 }
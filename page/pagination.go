@@ -0,0 +1,212 @@
+package page
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Pagination sets the keys that turn the virtual sub-pages of pages with
+// more buttons than the device has keys. They default to the first and the
+// last key of the bottom row. Style names the style of the keys.
+type Pagination struct {
+	Previous *Pos
+	Next     *Pos
+	Style    string
+}
+
+// keys returns the previous and the next key on the grid.
+func (n Pagination) keys(grid Grid) (previous, next int, err error) {
+	// The bottom row of some models has fewer keys.
+	previous, next = (grid.Keys-1)/grid.Columns*grid.Columns, grid.Keys-1
+	if n.Previous != nil {
+		previous, err = grid.key("previous", *n.Previous)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if n.Next != nil {
+		next, err = grid.key("next", *n.Next)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if previous == next {
+		return 0, 0, errors.New("pagination: previous and next are the same key")
+	}
+	return previous, next, nil
+}
+
+// key returns the key at the position, which must be one of the keys of
+// the device.
+func (g Grid) key(name string, pos Pos) (int, error) {
+	key, ok := g.index(pos.Row, pos.Col)
+	if !ok || key >= g.Keys {
+		return 0, fmt.Errorf("pagination: %s key row %d column %d is outside the %d keys of the device", name, pos.Row, pos.Col, g.Keys)
+	}
+	return key, nil
+}
+
+// Paginate splits the page into virtual sub-pages when Place left buttons
+// that do not fit on the device. With layout auto the buttons placed by
+// index or pos stay where they are on every sub-page, and the others flow,
+// in order, into the keys left free, across as many sub-pages as they need.
+// Without it every button flows, in the order of the indices. The previous
+// and next keys turn the sub-pages, in a loop, and show which one is shown
+// like "2/3". They are kept free: a button placed on one flows too.
+//
+// The first sub-page is the page itself and the others are named after it,
+// like "name/2". A page without such buttons is returned as it is.
+func (p *Page) Paginate(grid Grid, nav Pagination) ([]*Page, error) {
+	var placed, overflow []*Button
+	for i := range p.Buttons {
+		if p.Buttons[i].overflow {
+			overflow = append(overflow, &p.Buttons[i])
+		} else {
+			placed = append(placed, &p.Buttons[i])
+		}
+	}
+	if len(overflow) == 0 {
+		return []*Page{p}, nil
+	}
+
+	// Buttons that fit in the free keys need no sub-pages.
+	chunks, err := flowButtons(grid, grid.usedKeys(placed), p.flowOrder(overflow))
+	if err == nil && len(chunks) == 1 {
+		p.Buttons = append(buttonCopies(placed), chunks[0]...)
+		return []*Page{p}, nil
+	}
+
+	previous, next, err := nav.keys(grid)
+	if err != nil {
+		return nil, fmt.Errorf("page %s: %w", p.Name, err)
+	}
+	var fixed []*Button
+	overflow = overflow[:0]
+	for i := range p.Buttons {
+		button := &p.Buttons[i]
+		keys := grid.usedKeys([]*Button{button})
+		if p.Layout != "auto" || button.flows || button.overflow || keys[previous] || keys[next] {
+			overflow = append(overflow, button)
+			continue
+		}
+		fixed = append(fixed, button)
+	}
+	used := grid.usedKeys(fixed)
+	used[previous], used[next] = true, true
+	chunks, err = flowButtons(grid, used, p.flowOrder(overflow))
+	if err != nil {
+		return nil, fmt.Errorf("page %s: %w", p.Name, err)
+	}
+
+	pages := make([]*Page, len(chunks))
+	for i := range chunks {
+		sub := p
+		if i > 0 {
+			sub = &Page{
+				Name:       fmt.Sprintf("%s/%d", p.Name, i+1),
+				Theme:      p.Theme,
+				NightTheme: p.NightTheme,
+				Layout:     p.Layout,
+				Background: p.Background,
+				file:       p.file,
+				doc:        p.doc,
+			}
+		}
+		pages[i] = sub
+	}
+	for i, chunk := range chunks {
+		indicator := fmt.Sprintf("%d/%d", i+1, len(chunks))
+		buttons := append(buttonCopies(fixed), chunk...)
+		buttons = append(buttons,
+			navButton(previous, "←", indicator, pages[(i+len(pages)-1)%len(pages)].Name, nav.Style),
+			navButton(next, "→", indicator, pages[(i+1)%len(pages)].Name, nav.Style),
+		)
+		pages[i].Buttons = buttons
+	}
+	return pages, nil
+}
+
+// flowOrder returns the buttons in the order they flow in: the order of the
+// page with layout auto, and of the indices without.
+func (p *Page) flowOrder(buttons []*Button) []*Button {
+	if p.Layout != "auto" {
+		slices.SortStableFunc(buttons, func(a, b *Button) int {
+			return cmp.Compare(a.Index, b.Index)
+		})
+	}
+	return buttons
+}
+
+// flowButtons places copies of the buttons, in order, into the keys that
+// are not used, starting a new set of keys when they run out. A button
+// whose span does not fit even in a new set covers a single key.
+func flowButtons(grid Grid, used map[int]bool, buttons []*Button) ([][]Button, error) {
+	var chunks [][]Button
+	var chunk []Button
+	free := maps.Clone(used)
+	key := 0
+	for i := 0; i < len(buttons); {
+		placed := *buttons[i].lookCopy()
+		if len(chunk) == 0 && !grid.fitsAnywhere(free, &placed) {
+			placed.Span = nil
+		}
+		for key < grid.Keys && !grid.fits(free, key, &placed) {
+			key++
+		}
+		if key < grid.Keys {
+			placed.Index = uint8(key)
+			placed.overflow = false
+			for _, k := range grid.spanKeys(key, grid.span(key, &placed)) {
+				free[k] = true
+			}
+			chunk = append(chunk, placed)
+			i++
+			continue
+		}
+		if len(chunk) == 0 {
+			return nil, errors.New("no free keys left between the pagination keys")
+		}
+		chunks = append(chunks, chunk)
+		chunk = nil
+		free = maps.Clone(used)
+		key = 0
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// fitsAnywhere reports whether the button fits somewhere on the grid.
+func (g Grid) fitsAnywhere(used map[int]bool, b *Button) bool {
+	for key := 0; key < g.Keys; key++ {
+		if g.fits(used, key, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// buttonCopies returns copies of the buttons that do not share what styles
+// change, so every sub-page can be styled on its own.
+func buttonCopies(buttons []*Button) []Button {
+	copies := make([]Button, len(buttons))
+	for i, button := range buttons {
+		copies[i] = *button.lookCopy()
+	}
+	return copies
+}
+
+// navButton returns a key that turns to the target sub-page.
+func navButton(key int, arrow string, indicator string, target string, style string) Button {
+	return Button{
+		Index:    uint8(key),
+		Style:    style,
+		Label:    Label{Text: arrow, Align: "center", FontSize: 20},
+		Subtitle: &Label{Text: indicator},
+		Action:   Action{Type: "set_page", Value: []string{target}},
+	}
+}
@@ -0,0 +1,368 @@
+package page
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// flowingPage returns a page with layout auto, the buttons given and then
+// n flowing buttons.
+func flowingPage(t *testing.T, grid Grid, buttons string, n int) *Page {
+	t.Helper()
+	var src strings.Builder
+	src.WriteString("name: long\nlayout: auto\nbuttons:\n" + buttons)
+	for i := range n {
+		fmt.Fprintf(&src, "  - label: {text: b%d}\n", i+1)
+	}
+	p := loadPage(t, src.String())
+	err := p.Place(grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestFlowButtons(t *testing.T) {
+	button := func() *Button { return &Button{} }
+	square := func() *Button { return &Button{Span: &Span{Cols: 2, Rows: 2}} }
+	tests := []struct {
+		name    string
+		used    []int
+		buttons []*Button
+		want    [][]int
+		wantErr bool
+	}{
+		{
+			name:    "one set",
+			buttons: []*Button{button(), button(), button()},
+			want:    [][]int{{0, 1, 2}},
+		},
+		{
+			name:    "around used keys",
+			used:    []int{3, 5},
+			buttons: []*Button{button(), button(), button(), button(), button()},
+			want:    [][]int{{0, 1, 2, 4}, {0}},
+		},
+		{
+			name:    "full sets",
+			used:    []int{3, 5},
+			buttons: []*Button{button(), button(), button(), button(), button(), button(), button(), button()},
+			want:    [][]int{{0, 1, 2, 4}, {0, 1, 2, 4}},
+		},
+		{
+			name:    "span in a new set",
+			used:    []int{5},
+			buttons: []*Button{button(), button(), square(), button()},
+			want:    [][]int{{0, 1}, {0, 2}},
+		},
+		{
+			name:    "span that never fits",
+			used:    []int{3, 5},
+			buttons: []*Button{square(), button()},
+			want:    [][]int{{0, 1}},
+		},
+		{
+			name:    "no free keys",
+			used:    []int{0, 1, 2, 3, 4, 5},
+			buttons: []*Button{button()},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used := make(map[int]bool)
+			for _, key := range tt.used {
+				used[key] = true
+			}
+			chunks, err := flowButtons(miniGrid, used, tt.buttons)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("flowButtons() = %v, want an error", chunks)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("flowButtons() error: %v", err)
+			}
+			var got [][]int
+			for _, chunk := range chunks {
+				got = append(got, indices(chunk))
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("flowButtons() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name    string
+		grid    Grid
+		nav     Pagination
+		fixed   string
+		flowing int
+		// want has the indices of the buttons of every sub-page, the
+		// previous and next keys last.
+		want    [][]int
+		wantErr string
+	}{
+		{
+			name:    "buttons that fit",
+			grid:    miniGrid,
+			flowing: 6,
+			want:    [][]int{{0, 1, 2, 3, 4, 5}},
+		},
+		{
+			name:    "two sub-pages",
+			grid:    miniGrid,
+			flowing: 7,
+			want:    [][]int{{0, 1, 2, 4, 3, 5}, {0, 1, 2, 3, 5}},
+		},
+		{
+			name:    "three sub-pages",
+			grid:    miniGrid,
+			flowing: 10,
+			want:    [][]int{{0, 1, 2, 4, 3, 5}, {0, 1, 2, 4, 3, 5}, {0, 1, 3, 5}},
+		},
+		{
+			name:    "placed buttons stay on every sub-page",
+			grid:    miniGrid,
+			fixed:   "  - index: 1\n",
+			flowing: 6,
+			want:    [][]int{{1, 0, 2, 4, 3, 5}, {1, 0, 2, 4, 3, 5}},
+		},
+		{
+			name:    "pagination keys",
+			grid:    miniGrid,
+			nav:     Pagination{Previous: &Pos{Row: 0, Col: 0}, Next: &Pos{Row: 0, Col: -1}},
+			flowing: 7,
+			want:    [][]int{{1, 3, 4, 5, 0, 2}, {1, 3, 4, 0, 2}},
+		},
+		{
+			name:    "default keys on a partial bottom row",
+			grid:    neoGrid,
+			flowing: 11,
+			want:    [][]int{{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, {0, 1, 2, 8, 9}},
+		},
+		{
+			name:    "placed button on a pagination key flows",
+			grid:    miniGrid,
+			fixed:   "  - index: 5\n",
+			flowing: 6,
+			want:    [][]int{{0, 1, 2, 4, 3, 5}, {0, 1, 2, 3, 5}},
+		},
+		{
+			name:    "index past the keys takes a free key",
+			grid:    miniGrid,
+			fixed:   "  - index: 7\n",
+			flowing: 5,
+			want:    [][]int{{0, 1, 2, 3, 4, 5}},
+		},
+		{
+			name:    "last key of a partial bottom row",
+			grid:    neoGrid,
+			nav:     Pagination{Previous: &Pos{Row: 0, Col: 0}, Next: &Pos{Row: -1, Col: -1}},
+			flowing: 11,
+			want:    [][]int{{1, 2, 3, 4, 5, 6, 7, 8, 0, 9}, {1, 2, 3, 0, 9}},
+		},
+		{
+			name:    "same pagination keys",
+			grid:    miniGrid,
+			nav:     Pagination{Previous: &Pos{Row: 1, Col: 0}, Next: &Pos{Row: -1, Col: -3}},
+			flowing: 7,
+			wantErr: "previous and next are the same key",
+		},
+		{
+			name:    "pagination key outside the device",
+			grid:    neoGrid,
+			nav:     Pagination{Next: &Pos{Row: -1, Col: 2}},
+			flowing: 11,
+			wantErr: "next key row -1 column 2 is outside the 10 keys of the device",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := flowingPage(t, tt.grid, tt.fixed, tt.flowing)
+			pages, err := p.Paginate(tt.grid, tt.nav)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Paginate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Paginate() error: %v", err)
+			}
+			var got [][]int
+			for _, sub := range pages {
+				got = append(got, indices(sub.Buttons))
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("Paginate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginateNavigation(t *testing.T) {
+	p := flowingPage(t, miniGrid, "", 10)
+	pages, err := p.Paginate(miniGrid, Pagination{Style: "nav"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages[0] != p {
+		t.Errorf("the first sub-page is not the page itself")
+	}
+	names := []string{"long", "long/2", "long/3"}
+	for i, sub := range pages {
+		if sub.Name != names[i] {
+			t.Errorf("sub-page %d is named %q, want %q", i, sub.Name, names[i])
+		}
+		buttons := sub.Buttons[len(sub.Buttons)-2:]
+		previous, next := names[(i+2)%3], names[(i+1)%3]
+		indicator := fmt.Sprintf("%d/3", i+1)
+		for j, target := range []string{previous, next} {
+			nav := buttons[j]
+			if nav.Action.Type != "set_page" || !slices.Equal(nav.Action.Value, []string{target}) {
+				t.Errorf("sub-page %d key %d turns to %v, want %q", i, nav.Index, nav.Action.Value, target)
+			}
+			if nav.Subtitle == nil || nav.Subtitle.Text != indicator {
+				t.Errorf("sub-page %d key %d shows %v, want %q", i, nav.Index, nav.Subtitle, indicator)
+			}
+			if nav.Style != "nav" {
+				t.Errorf("sub-page %d key %d has style %q, want %q", i, nav.Index, nav.Style, "nav")
+			}
+		}
+	}
+}
+
+func TestPaginateFullPage(t *testing.T) {
+	// A page made for the 15 keys of the original model.
+	var src strings.Builder
+	src.WriteString("name: full\nbuttons:\n")
+	for i := 14; i >= 0; i-- {
+		fmt.Fprintf(&src, "  - index: %d\n    label: {text: b%d}\n", i, i)
+	}
+	tests := []struct {
+		name string
+		grid Grid
+		// want has the indices of the buttons of every sub-page, the
+		// previous and next keys last.
+		want [][]int
+	}{
+		{
+			name: "mini",
+			grid: miniGrid,
+			want: [][]int{{0, 1, 2, 4, 3, 5}, {0, 1, 2, 4, 3, 5}, {0, 1, 2, 4, 3, 5}, {0, 1, 2, 3, 5}},
+		},
+		{
+			name: "neo",
+			grid: neoGrid,
+			want: [][]int{{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, {0, 1, 2, 3, 4, 5, 6, 8, 9}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := loadPage(t, src.String())
+			err := p.Place(tt.grid)
+			if err == nil {
+				err = p.Check(&Checker{Grid: tt.grid})
+			}
+			if err != nil {
+				t.Fatalf("Place() and Check() error: %v", err)
+			}
+			pages, err := p.Paginate(tt.grid, Pagination{})
+			if err != nil {
+				t.Fatalf("Paginate() error: %v", err)
+			}
+			var got [][]int
+			var texts []string
+			for _, sub := range pages {
+				got = append(got, indices(sub.Buttons))
+				for _, button := range sub.Buttons[:len(sub.Buttons)-2] {
+					texts = append(texts, button.Label.Text)
+				}
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("Paginate() = %v, want %v", got, tt.want)
+			}
+			// The buttons follow their indices across the sub-pages.
+			for i, text := range texts {
+				if want := fmt.Sprintf("b%d", i); text != want {
+					t.Errorf("button %d is %q, want %q", i, text, want)
+				}
+			}
+			if len(texts) != 15 {
+				t.Errorf("sub-pages have %d buttons, want 15", len(texts))
+			}
+		})
+	}
+}
+
+func TestCheckKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		grid    Grid
+		src     string
+		wantErr string
+	}{
+		{
+			name: "index on the device",
+			grid: miniGrid,
+			src:  "buttons:\n  - index: 5\n",
+		},
+		{
+			name: "index beyond the keys",
+			grid: miniGrid,
+			src:  "buttons:\n  - index: 6\n",
+		},
+		{
+			name: "index beyond the keys with layout auto",
+			grid: miniGrid,
+			src:  "layout: auto\nbuttons:\n  - index: 7\n  - label: {text: a}\n",
+		},
+		{
+			name:    "duplicate index beyond the keys",
+			grid:    miniGrid,
+			src:     "buttons:\n  - index: 7\n  - index: 7\n",
+			wantErr: "page.yml:4:12: duplicate index 7, also on line 3",
+		},
+		{
+			name: "pos on the partial bottom row",
+			grid: neoGrid,
+			src:  "buttons:\n  - pos: {row: -1, col: -1}\n",
+		},
+		{
+			name:    "pos past the partial bottom row",
+			grid:    neoGrid,
+			src:     "buttons:\n  - pos: {row: -1, col: 2}\n",
+			wantErr: "page.yml:3:10: row -1 column 2 is outside the 4x3 keys of the device",
+		},
+		{
+			name: "flowing buttons beyond the keys",
+			grid: miniGrid,
+			src:  "layout: auto\nbuttons:\n" + strings.Repeat("  - label: {text: a}\n", 8),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := loadPage(t, "name: keys\n"+tt.src)
+			err := p.Place(tt.grid)
+			if err == nil {
+				err = p.Check(&Checker{Grid: tt.grid})
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Check() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package page
+
+import (
+	"reflect"
+	"time"
+)
+
+const defaultStateRefresh = 2 * time.Second
+
+// State is one appearance and action of a multi-state button. Icon and
+// Label fall back to the ones of the button when they are not set.
+type State struct {
+	Name   string
+	Icon   Icon
+	Label  Label
+	Action Action
+}
+
+func (b *Button) StateRefreshInterval() time.Duration {
+	if b.StateRefresh <= 0 {
+		return defaultStateRefresh
+	}
+	return b.StateRefresh
+}
+
+// StateView returns the icon and label shown in the given state.
+func (b *Button) StateView(state int) (Icon, Label) {
+	icon, label := b.Icon, b.Label
+	if state < 0 || state >= len(b.States) {
+		return icon, label
+	}
+	if !b.States[state].Icon.isZero() {
+		icon = b.States[state].Icon
+	}
+	if !b.States[state].Label.isZero() {
+		label = b.States[state].Label
+	}
+	return icon, label
+}
+
+func (i Icon) isZero() bool {
+	return reflect.DeepEqual(i, Icon{})
+}
+
+func (l Label) isZero() bool {
+	return reflect.DeepEqual(l, Label{})
+}
@@ -0,0 +1,197 @@
+package page
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Style is a set of defaults for the look of buttons. Values set on a
+// button, or on its labels and icons, take precedence.
+type Style struct {
+	Font          string
+	FontSize      int    `yaml:"font_size"`
+	FontColor     string `yaml:"font_color"`
+	OutlineColor  string `yaml:"outline_color"`
+	OutlineWidth  int    `yaml:"outline_width"`
+	Fill          string
+	Tint          string
+	IconPadding   int    `yaml:"icon_padding"`
+	PressedFill   string `yaml:"pressed_fill"`
+	PressedEffect string `yaml:"pressed_effect"`
+}
+
+// Theme is the default style of the buttons of the deck or of a page, and
+// the named styles buttons refer to with style.
+type Theme struct {
+	Style  `yaml:",inline"`
+	Styles map[string]Style
+}
+
+// over returns the style with the values it does not set taken from base.
+func (s Style) over(base Style) Style {
+	if s.Font == "" {
+		s.Font = base.Font
+	}
+	if s.FontSize == 0 {
+		s.FontSize = base.FontSize
+	}
+	if s.FontColor == "" {
+		s.FontColor = base.FontColor
+	}
+	if s.OutlineColor == "" {
+		s.OutlineColor = base.OutlineColor
+	}
+	if s.OutlineWidth == 0 {
+		s.OutlineWidth = base.OutlineWidth
+	}
+	if s.Fill == "" {
+		s.Fill = base.Fill
+	}
+	if s.Tint == "" {
+		s.Tint = base.Tint
+	}
+	if s.IconPadding == 0 {
+		s.IconPadding = base.IconPadding
+	}
+	if s.PressedFill == "" {
+		s.PressedFill = base.PressedFill
+	}
+	if s.PressedEffect == "" {
+		s.PressedEffect = base.PressedEffect
+	}
+	return s
+}
+
+// Inherit returns the theme with the defaults it does not set taken from
+// parent. Named styles of both are available, and a style defined in both
+// is merged, the values of the theme winning.
+func (t Theme) Inherit(parent Theme) Theme {
+	styles := make(map[string]Style, len(parent.Styles)+len(t.Styles))
+	for name, style := range parent.Styles {
+		styles[name] = style
+	}
+	for name, style := range t.Styles {
+		styles[name] = style.over(parent.Styles[name])
+	}
+	return Theme{Style: t.Style.over(parent.Style), Styles: styles}
+}
+
+// ButtonStyle returns the named style over the defaults of the theme. An
+// empty name returns the defaults.
+func (t Theme) ButtonStyle(name string) (Style, error) {
+	if name == "" {
+		return t.Style, nil
+	}
+	style, exists := t.Styles[name]
+	if !exists {
+		return Style{}, fmt.Errorf("unknown style: %s", name)
+	}
+	return style.over(t.Style), nil
+}
+
+// ApplyTheme gives the buttons the defaults of the theme of the page and
+// of the styles they name. Applying another theme later replaces the
+// defaults of the previous one. Buttons naming an unknown style get the
+// defaults of the theme and the style is reported.
+func (p *Page) ApplyTheme(theme Theme) error {
+	var errs []error
+	for i := range p.Buttons {
+		button := &p.Buttons[i]
+		style, err := theme.ButtonStyle(button.Style)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("page %s button %d: %w", p.Name, button.Index, err))
+			style = theme.Style
+		}
+		if button.unstyled == nil {
+			button.unstyled = button.lookCopy()
+		} else {
+			button.unstyle()
+		}
+		button.applyStyle(style)
+	}
+	return errors.Join(errs...)
+}
+
+// lookCopy returns a copy of the button that does not share what styles
+// change with it.
+func (b *Button) lookCopy() *Button {
+	c := *b
+	c.States = slices.Clone(b.States)
+	if b.Subtitle != nil {
+		subtitle := *b.Subtitle
+		c.Subtitle = &subtitle
+	}
+	return &c
+}
+
+// unstyle puts back the look the button had before a style was applied.
+func (b *Button) unstyle() {
+	u := b.unstyled
+	b.FontSize = u.FontSize
+	b.PressedFill, b.PressedEffect = u.PressedFill, u.PressedEffect
+	b.Icon, b.Label = u.Icon, u.Label
+	if u.Subtitle != nil {
+		subtitle := *u.Subtitle
+		b.Subtitle = &subtitle
+	}
+	for i := range b.States {
+		b.States[i].Icon, b.States[i].Label = u.States[i].Icon, u.States[i].Label
+	}
+}
+
+// applyStyle fills what the button does not set from the style. States
+// without an icon or label of their own keep showing the ones of the
+// button, so only the set ones are styled.
+func (b *Button) applyStyle(s Style) {
+	if b.FontSize == 0 {
+		b.FontSize = s.FontSize
+	}
+	if b.PressedIcon == nil && b.PressedFill == "" {
+		b.PressedFill = s.PressedFill
+	}
+	if b.PressedEffect == "" {
+		b.PressedEffect = s.PressedEffect
+	}
+	s.applyToIcon(&b.Icon)
+	s.applyToLabel(&b.Label)
+	if b.Subtitle != nil {
+		s.applyToLabel(b.Subtitle)
+	}
+	for i := range b.States {
+		state := &b.States[i]
+		if !state.Icon.isZero() {
+			s.applyToIcon(&state.Icon)
+		}
+		if !state.Label.isZero() {
+			s.applyToLabel(&state.Label)
+		}
+	}
+}
+
+func (s Style) applyToIcon(icon *Icon) {
+	if icon.Fill == "" {
+		icon.Fill = s.Fill
+	}
+	if icon.Tint == "" {
+		icon.Tint = s.Tint
+	}
+	if icon.Padding == 0 {
+		icon.Padding = s.IconPadding
+	}
+}
+
+func (s Style) applyToLabel(label *Label) {
+	if label.Font == "" {
+		label.Font = s.Font
+	}
+	if label.FontColor == "" {
+		label.FontColor = s.FontColor
+	}
+	if label.OutlineColor == "" {
+		label.OutlineColor = s.OutlineColor
+	}
+	if label.OutlineWidth == 0 {
+		label.OutlineWidth = s.OutlineWidth
+	}
+}
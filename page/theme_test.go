@@ -0,0 +1,126 @@
+package page
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestThemeInherit(t *testing.T) {
+	deck := Theme{
+		Style: Style{Font: "Sans", FontSize: 12, FontColor: "white", Fill: "black"},
+		Styles: map[string]Style{
+			"danger": {Fill: "red", FontColor: "yellow"},
+			"muted":  {FontColor: "gray"},
+		},
+	}
+	p := Theme{
+		Style: Style{FontSize: 16, Fill: "navy"},
+		Styles: map[string]Style{
+			"danger": {Fill: "maroon"},
+			"accent": {Fill: "orange"},
+		},
+	}
+	got := p.Inherit(deck)
+
+	want := Style{Font: "Sans", FontSize: 16, FontColor: "white", Fill: "navy"}
+	if got.Style != want {
+		t.Errorf("Inherit() defaults = %+v, want %+v", got.Style, want)
+	}
+	styles := map[string]Style{
+		// A style defined in both is merged, the page winning.
+		"danger": {Fill: "maroon", FontColor: "yellow"},
+		"muted":  {FontColor: "gray"},
+		"accent": {Fill: "orange"},
+	}
+	if len(got.Styles) != len(styles) {
+		t.Errorf("Inherit() styles = %v, want %v", got.Styles, styles)
+	}
+	for name, style := range styles {
+		if got.Styles[name] != style {
+			t.Errorf("Inherit() style %s = %+v, want %+v", name, got.Styles[name], style)
+		}
+	}
+	if deck.Styles["danger"].Fill != "red" {
+		t.Errorf("Inherit() changed the parent styles")
+	}
+}
+
+func TestThemeButtonStyle(t *testing.T) {
+	theme := Theme{
+		Style:  Style{FontColor: "white", Fill: "black"},
+		Styles: map[string]Style{"danger": {Fill: "red"}},
+	}
+	style, err := theme.ButtonStyle("")
+	if err != nil || style != theme.Style {
+		t.Errorf("ButtonStyle(\"\") = %+v, %v, want the defaults", style, err)
+	}
+	style, err = theme.ButtonStyle("danger")
+	want := Style{FontColor: "white", Fill: "red"}
+	if err != nil || style != want {
+		t.Errorf("ButtonStyle(danger) = %+v, %v, want %+v", style, err, want)
+	}
+	_, err = theme.ButtonStyle("missing")
+	if err == nil || err.Error() != "unknown style: missing" {
+		t.Errorf("ButtonStyle(missing) error = %v", err)
+	}
+}
+
+func TestApplyTheme(t *testing.T) {
+	p := loadPage(t, `name: themed
+buttons:
+  - index: 0
+    label: {text: a}
+    subtitle: {text: b}
+  - index: 1
+    style: danger
+    font_size: 20
+    icon: {fill: green}
+    label: {text: c, font_color: blue}
+    states:
+      - label: {text: on}
+      - icon: {file: off.png}
+`)
+	day := Theme{
+		Style:  Style{FontSize: 12, FontColor: "white", Fill: "black"},
+		Styles: map[string]Style{"danger": {Fill: "red", FontColor: "yellow"}},
+	}
+	night := Theme{
+		Style:  Style{FontColor: "gray"},
+		Styles: map[string]Style{"danger": {Fill: "maroon"}},
+	}
+	err := p.ApplyTheme(day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, styled := &p.Buttons[0], &p.Buttons[1]
+	if plain.FontSize != 12 || plain.Icon.Fill != "black" || plain.Label.FontColor != "white" || plain.Subtitle.FontColor != "white" {
+		t.Errorf("plain button = size %d fill %q color %q subtitle %q", plain.FontSize, plain.Icon.Fill, plain.Label.FontColor, plain.Subtitle.FontColor)
+	}
+	// Values set on the button win over the style.
+	if styled.FontSize != 20 || styled.Icon.Fill != "green" || styled.Label.FontColor != "blue" {
+		t.Errorf("styled button = size %d fill %q color %q", styled.FontSize, styled.Icon.Fill, styled.Label.FontColor)
+	}
+	// Only what a state sets is styled.
+	on, off := styled.States[0], styled.States[1]
+	if on.Label.FontColor != "yellow" || on.Icon.Fill != "" || off.Icon.Fill != "red" || off.Label.FontColor != "" {
+		t.Errorf("states = %+v, %+v", on, off)
+	}
+
+	// Another theme replaces the defaults of the first one.
+	err = p.ApplyTheme(night)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.FontSize != 0 || plain.Icon.Fill != "" || plain.Label.FontColor != "gray" {
+		t.Errorf("plain button at night = size %d fill %q color %q", plain.FontSize, plain.Icon.Fill, plain.Label.FontColor)
+	}
+	if styled.States[1].Icon.Fill != "maroon" || styled.States[0].Label.FontColor != "gray" {
+		t.Errorf("states at night = %+v", styled.States)
+	}
+
+	p.Buttons[0].Style = "missing"
+	err = p.ApplyTheme(day)
+	if err == nil || !strings.Contains(err.Error(), "page themed button 0: unknown style: missing") {
+		t.Errorf("ApplyTheme() error = %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package page
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Keys whose values are fills, which may be gradients, and keys whose
+// values are single colours.
+var (
+	paintKeys = map[string]bool{
+		"fill":         true,
+		"pressed_fill": true,
+		"work_fill":    true,
+		"break_fill":   true,
+	}
+	colorKeys = map[string]bool{
+		"font_color":    true,
+		"outline_color": true,
+		"background":    true,
+		"tint":          true,
+		"color":         true,
+	}
+)
+
+// unknownField matches the error of the YAML decoder for a key that is not
+// a field of the type decoded.
+var unknownField = regexp.MustCompile(`^line (\d+): field (.+) not found in type (\S+)$`)
+
+// DecodeFile reads a YAML file into out and returns its document, which
+// locates values for later checks. Invalid colours and unknown keys are
+// reported with the file, line and column they are on. They do not stop
+// the decoding: the document is returned with them and out holds the rest
+// of the file. The document is nil when the file cannot be read or parsed.
+func DecodeFile(path string, out any) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	err = yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	errs := []error{validateColors(path, &doc)}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(out)
+	var typeErr *yaml.TypeError
+	switch {
+	case errors.As(err, &typeErr):
+		errs = append(errs, fieldErrors(path, &doc, typeErr))
+	case err != nil && !errors.Is(err, io.EOF):
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &doc, errors.Join(errs...)
+}
+
+// fieldErrors reports the unknown keys of a decoding error with the file,
+// line and column they are on, and the other errors with the file.
+func fieldErrors(file string, doc *yaml.Node, typeErr *yaml.TypeError) error {
+	var errs []error
+	for _, message := range typeErr.Errors {
+		match := unknownField.FindStringSubmatch(message)
+		if match == nil {
+			errs = append(errs, fmt.Errorf("%s: %s", file, message))
+			continue
+		}
+		line, _ := strconv.Atoi(match[1])
+		key := findKey(doc, line, match[2])
+		if key == nil {
+			errs = append(errs, fmt.Errorf("%s:%d: unknown key %q", file, line, match[2]))
+			continue
+		}
+		errs = append(errs, NodeError(file, key, "unknown key %q", match[2]))
+	}
+	return errors.Join(errs...)
+}
+
+// findKey returns the mapping key named name on the line, or nil.
+func findKey(node *yaml.Node, line int, name string) *yaml.Node {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key := node.Content[i]; key.Line == line && key.Value == name {
+				return key
+			}
+		}
+	}
+	for _, child := range node.Content {
+		if key := findKey(child, line, name); key != nil {
+			return key
+		}
+	}
+	return nil
+}
+
+// ConfigError is a problem found at a line and column of a configuration
+// file.
+type ConfigError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// NodeError returns an error prefixed with the file, line and column of the
+// node.
+func NodeError(file string, node *yaml.Node, format string, args ...any) error {
+	return &ConfigError{File: file, Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)}
+}
+
+// ConfigErrors returns the problems with a file, line and column found in
+// err, which may join and wrap them.
+func ConfigErrors(err error) []*ConfigError {
+	switch e := err.(type) {
+	case *ConfigError:
+		return []*ConfigError{e}
+	case interface{ Unwrap() []error }:
+		var found []*ConfigError
+		for _, err := range e.Unwrap() {
+			found = append(found, ConfigErrors(err)...)
+		}
+		return found
+	case interface{ Unwrap() error }:
+		return ConfigErrors(e.Unwrap())
+	}
+	return nil
+}
+
+// LookupNode returns the value of the key in the mapping node, or of the
+// mapping at the root of the document node. It returns nil when the key is
+// not set.
+func LookupNode(node *yaml.Node, key string) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// validateColors checks every colour in the YAML document and reports the
+// invalid ones with the file, line and column they are on.
+func validateColors(file string, node *yaml.Node) error {
+	var errs []error
+	walkColors(node, func(key string, value *yaml.Node, paint bool) {
+		var err error
+		switch {
+		case paint:
+			_, err = ParsePaint(value.Value)
+		case key == "font_color" && value.Value == "auto":
+		default:
+			_, err = ParseColor(value.Value)
+		}
+		if err != nil {
+			errs = append(errs, NodeError(file, value, "%s: %v", key, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// walkColors calls check for every scalar value of a colour key.
+func walkColors(node *yaml.Node, check func(key string, value *yaml.Node, paint bool)) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			walkColors(child, check)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			if value.Kind != yaml.ScalarNode {
+				walkColors(value, check)
+				continue
+			}
+			if value.Tag == "!!null" {
+				continue
+			}
+			switch {
+			case paintKeys[key]:
+				check(key, value, true)
+			case colorKeys[key]:
+				check(key, value, false)
+			}
+		}
+	}
+}
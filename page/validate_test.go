@@ -0,0 +1,147 @@
+package page
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePage writes the page YAML to a temporary file and returns its path.
+func writePage(t *testing.T, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "page.yml")
+	err := os.WriteFile(path, []byte(src), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPageProblems(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		wantErrs []string
+		buttons  int
+	}{
+		{
+			name:    "valid",
+			src:     "name: main\nbuttons:\n  - index: 0\n    icon: {fill: red}\n",
+			buttons: 1,
+		},
+		{
+			name:     "unknown key",
+			src:      "name: main\nbuttons:\n  - index: 0\n    lable: {text: Hi}\n",
+			wantErrs: []string{"page.yml:4:5: unknown key \"lable\""},
+			buttons:  1,
+		},
+		{
+			name:     "invalid colour",
+			src:      "name: main\nbuttons:\n  - index: 0\n    icon: {fill: \"#12345\"}\n",
+			wantErrs: []string{"page.yml:4:18: fill: invalid hex color"},
+			buttons:  1,
+		},
+		{
+			name: "several problems",
+			src: "name: main\nbuttons:\n  - index: 0\n    label: {text: A, font_color: nope}\n" +
+				"  - index: 1\n    colour: red\n",
+			wantErrs: []string{"page.yml:4:34: font_color: unknown color", "page.yml:6:5: unknown key \"colour\""},
+			buttons:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPage()
+			err := p.LoadPage(writePage(t, tt.src))
+			if !p.Loaded() {
+				t.Fatalf("LoadPage() did not load the page: %v", err)
+			}
+			if len(p.Buttons) != tt.buttons {
+				t.Errorf("len(Buttons) = %d, want %d", len(p.Buttons), tt.buttons)
+			}
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("LoadPage() error: %v", err)
+				}
+				return
+			}
+			problems := ConfigErrors(err)
+			if len(problems) != len(tt.wantErrs) {
+				t.Fatalf("ConfigErrors() = %v, want %d problems", problems, len(tt.wantErrs))
+			}
+			for i, want := range tt.wantErrs {
+				if got := problems[i].Error(); !strings.Contains(got, want) {
+					t.Errorf("problem %d = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPageFailures(t *testing.T) {
+	p := NewPage()
+	err := p.LoadPage(filepath.Join(t.TempDir(), "missing.yml"))
+	if err == nil || p.Loaded() {
+		t.Errorf("LoadPage() of a missing file = %v, loaded %v", err, p.Loaded())
+	}
+
+	p = NewPage()
+	err = p.LoadPage(writePage(t, "name: main\nbuttons: [\n"))
+	if err == nil || p.Loaded() {
+		t.Errorf("LoadPage() of invalid YAML = %v, loaded %v", err, p.Loaded())
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	p := NewPage()
+	err := p.LoadPage(writePage(t, "name: main\nbogus: 1\n"))
+	problems := ConfigErrors(err)
+	if len(problems) != 1 {
+		t.Fatalf("ConfigErrors() = %v, want one problem", problems)
+	}
+	got := problems[0]
+	if got.Line != 2 || got.Column != 1 || got.Message != `unknown key "bogus"` {
+		t.Errorf("ConfigErrors() = %+v, want line 2 column 1 unknown key", got)
+	}
+	if ConfigErrors(nil) != nil {
+		t.Error("ConfigErrors(nil) is not nil")
+	}
+}
+
+func TestSkipBroken(t *testing.T) {
+	src := "name: main\nlayout: auto\nbuttons:\n" +
+		"  - label: {text: A}\n" +
+		"  - label: {text: B}\n    icon: {fill: nope}\n" +
+		"  - label: {text: C}\n    lable: {text: typo}\n" +
+		"  - label: {text: D}\n"
+	p := NewPage()
+	err := p.LoadPage(writePage(t, src))
+	if err == nil {
+		t.Fatal("LoadPage() did not report the problems")
+	}
+	if removed := p.SkipBroken(err); removed != 2 {
+		t.Fatalf("SkipBroken() = %d, want 2", removed)
+	}
+	var texts []string
+	for _, button := range p.Buttons {
+		texts = append(texts, button.Label.Text)
+	}
+	if strings.Join(texts, ",") != "A,D" {
+		t.Errorf("buttons left = %v, want A and D", texts)
+	}
+	if nodes := p.buttonNodes(); len(nodes) != 2 {
+		t.Fatalf("button nodes left = %d, want 2", len(nodes))
+	}
+
+	err = p.Place(originalGrid)
+	if err != nil {
+		t.Fatalf("Place() error: %v", err)
+	}
+	if got := indices(p.Buttons); got[0] != 0 || got[1] != 1 {
+		t.Errorf("indices after skipping = %v, want [0 1]", got)
+	}
+	if removed := p.SkipBroken(err); removed != 0 {
+		t.Errorf("SkipBroken() without problems = %d, want 0", removed)
+	}
+}
@@ -0,0 +1,65 @@
+package page
+
+import "time"
+
+const defaultWidgetRefresh = time.Second
+
+type Widget struct {
+	Type       string
+	Source     string
+	Direction  string
+	Command    []string
+	Display    string
+	ChartStyle string `yaml:"chart_style"`
+	History    int
+	Span       int
+	Color      string
+	Max        float64
+	Refresh    time.Duration
+	Thresholds []Threshold
+}
+
+// Threshold changes the widget colour when the value is above or below the
+// given limit. When several thresholds match, the last one wins.
+type Threshold struct {
+	Above *float64
+	Below *float64
+	Color string
+}
+
+// Columns returns the number of keys the widget covers. Charts are drawn
+// across Span keys.
+func (w *Widget) Columns() int {
+	if w.Display == "chart" {
+		return max(w.Span, 1)
+	}
+	return 1
+}
+
+func (w *Widget) RefreshInterval() time.Duration {
+	if w.Refresh <= 0 {
+		return defaultWidgetRefresh
+	}
+	return w.Refresh
+}
+
+// AutoScale reports whether a chart should be scaled to the largest value in
+// its history, which is the case for command output without a known maximum.
+func (w *Widget) AutoScale() bool {
+	return w.Type == "command" && w.Max <= 0
+}
+
+// ColorFor returns the colour that should be used for the given value.
+func (w *Widget) ColorFor(value float64) string {
+	col := w.Color
+	for _, t := range w.Thresholds {
+		if t.Above != nil && value < *t.Above {
+			continue
+		}
+		if t.Below != nil && value > *t.Below {
+			continue
+		}
+		col = t.Color
+	}
+	return col
+}
@@ -0,0 +1,409 @@
+package streamdeck
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"time"
+
+	"angrysoft.ovh/angry-deck/page"
+)
+
+// Frames shown for less than minFrameDelay are shown for defaultFrameDelay
+// instead, like browsers do for GIFs without a real delay.
+const (
+	minFrameDelay     = 20 * time.Millisecond
+	defaultFrameDelay = 100 * time.Millisecond
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// Animation is a button with an animated icon: every frame of the icon
+// drawn like RenderButton draws still icons, and how long each is shown.
+type Animation struct {
+	Frames []image.Image
+	Delays []time.Duration
+}
+
+// RenderAnimation renders the frames of an animated GIF or APNG icon with
+// the fill, or the background, and the labels of the button. It returns nil
+// when the icon is not animated.
+func (dd *DeckDevice) RenderAnimation(dir string, background image.Image, icon page.Icon, labels ...page.Label) (*Animation, error) {
+	if icon.File == "" && icon.Theme == "" {
+		return nil, nil
+	}
+	size := int(dd.Pixels)
+	box := image.Rect(0, 0, size, size).Inset(icon.Padding)
+	if box.Empty() {
+		return nil, nil
+	}
+	path, err := dd.iconPath(dir, icon, max(box.Dx(), box.Dy()))
+	if err != nil {
+		return nil, err
+	}
+	frames, delays, err := decodeAnimation(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode animated icon: %w", err)
+	}
+	if len(frames) < 2 {
+		return nil, nil
+	}
+
+	animation := &Animation{Delays: delays}
+	for _, frame := range frames {
+		canvas, err := iconCanvas(image.Point{X: size, Y: size}, background, icon)
+		if err != nil {
+			return nil, err
+		}
+		scaled, err := scaleIcon(frame, icon.Scale, box)
+		if err != nil {
+			return nil, err
+		}
+		err = composeIcon(canvas, icon, box, scaled)
+		if err != nil {
+			return nil, err
+		}
+		err = dd.drawLayers(canvas, dir, icon.Layers, canvas.Bounds())
+		if err != nil {
+			return nil, err
+		}
+		img, err := dd.drawLabels(canvas, labels)
+		if err != nil {
+			return nil, err
+		}
+		animation.Frames = append(animation.Frames, img)
+	}
+	return animation, nil
+}
+
+// decodeAnimation returns the composed frames of an animated GIF or PNG and
+// their delays. Still images have no frames and are not decoded.
+func decodeAnimation(path string) ([]image.Image, []time.Duration, error) {
+	kind, err := animationKind(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch kind {
+	case "gif":
+		return decodeGIF(path)
+	case "png":
+		return decodeAPNG(path)
+	}
+	return nil, nil, nil
+}
+
+// animationKind tells from the header of the file whether it is an animated
+// GIF or PNG, reading the chunks or blocks but not the image data. It
+// returns an empty kind for other files.
+func animationKind(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	r := bufio.NewReader(file)
+	header, _ := r.Peek(len(pngSignature))
+	switch {
+	case bytes.HasPrefix(header, pngSignature):
+		animated, err := apngAnimated(r)
+		if err != nil || !animated {
+			return "", err
+		}
+		return "png", nil
+	case bytes.HasPrefix(header, []byte("GIF8")):
+		animated, err := gifAnimated(r)
+		if err != nil || !animated {
+			return "", err
+		}
+		return "gif", nil
+	}
+	return "", nil
+}
+
+// apngAnimated reports whether an animation control chunk comes before the
+// image data of the PNG.
+func apngAnimated(r *bufio.Reader) (bool, error) {
+	if _, err := r.Discard(len(pngSignature)); err != nil {
+		return false, err
+	}
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return false, err
+		}
+		switch string(header[4:]) {
+		case "acTL":
+			return true, nil
+		case "IDAT", "IEND":
+			return false, nil
+		}
+		// Skip the chunk body and its CRC.
+		if _, err := r.Discard(int(binary.BigEndian.Uint32(header[:])) + 4); err != nil {
+			return false, err
+		}
+	}
+}
+
+// gifAnimated reports whether the GIF has more than one image. The image
+// data is skipped, not decoded.
+func gifAnimated(r *bufio.Reader) (bool, error) {
+	var screen [13]byte
+	if _, err := io.ReadFull(r, screen[:]); err != nil {
+		return false, err
+	}
+	if err := skipColorTable(r, screen[10]); err != nil {
+		return false, err
+	}
+	images := 0
+	for {
+		block, err := r.ReadByte()
+		if err != nil {
+			return false, err
+		}
+		switch block {
+		case 0x21: // extension
+			if _, err := r.ReadByte(); err != nil {
+				return false, err
+			}
+			if err := skipSubBlocks(r); err != nil {
+				return false, err
+			}
+		case 0x2c: // image descriptor
+			var descriptor [9]byte
+			if _, err := io.ReadFull(r, descriptor[:]); err != nil {
+				return false, err
+			}
+			if err := skipColorTable(r, descriptor[8]); err != nil {
+				return false, err
+			}
+			// The LZW minimum code size comes before the image data.
+			if _, err := r.ReadByte(); err != nil {
+				return false, err
+			}
+			if err := skipSubBlocks(r); err != nil {
+				return false, err
+			}
+			images++
+			if images > 1 {
+				return true, nil
+			}
+		default: // trailer
+			return false, nil
+		}
+	}
+}
+
+func skipColorTable(r *bufio.Reader, flags byte) error {
+	if flags&0x80 == 0 {
+		return nil
+	}
+	_, err := r.Discard(3 << (flags&0x07 + 1))
+	return err
+}
+
+func skipSubBlocks(r *bufio.Reader) error {
+	for {
+		size, err := r.ReadByte()
+		if err != nil || size == 0 {
+			return err
+		}
+		if _, err := r.Discard(int(size)); err != nil {
+			return err
+		}
+	}
+}
+
+func decodeGIF(path string) ([]image.Image, []time.Duration, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	g, err := gif.DecodeAll(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(g.Image) < 2 {
+		return nil, nil, nil
+	}
+
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		bounds = g.Image[0].Bounds()
+	}
+	canvas := image.NewRGBA(bounds)
+	var frames []image.Image
+	var delays []time.Duration
+	for i, frame := range g.Image {
+		disposal := byte(gif.DisposalNone)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		frames = append(frames, cloneRGBA(canvas))
+		delays = append(delays, frameDelay(time.Duration(g.Delay[i])*10*time.Millisecond))
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return frames, delays, nil
+}
+
+// apngFrame is a frame control chunk with the image data of the frame.
+type apngFrame struct {
+	control []byte
+	data    []byte
+}
+
+// decodeAPNG composes the frames of an animated PNG. The image/png package
+// only decodes the default image, so each frame is rebuilt as a PNG of its
+// own and decoded.
+func decodeAPNG(path string) ([]image.Image, []time.Duration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, nil, errors.New("not a PNG file")
+	}
+
+	var ihdr []byte
+	var shared bytes.Buffer
+	var frames []*apngFrame
+	animated, seenData := false, false
+	for pos := len(pngSignature); pos+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		if pos+12+length > len(data) {
+			return nil, nil, errors.New("truncated PNG chunk")
+		}
+		kind := string(data[pos+4 : pos+8])
+		body := data[pos+8 : pos+8+length]
+		chunk := data[pos : pos+12+length]
+		pos += 12 + length
+
+		switch kind {
+		case "IHDR":
+			ihdr = body
+		case "acTL":
+			animated = true
+		case "fcTL":
+			if len(body) < 26 {
+				return nil, nil, errors.New("invalid fcTL chunk")
+			}
+			frames = append(frames, &apngFrame{control: body})
+		case "IDAT":
+			seenData = true
+			// The default image is the first frame only when a frame
+			// control chunk comes before it.
+			if len(frames) > 0 {
+				frames[len(frames)-1].data = append(frames[len(frames)-1].data, body...)
+			}
+		case "fdAT":
+			if len(frames) > 0 && len(body) >= 4 {
+				frames[len(frames)-1].data = append(frames[len(frames)-1].data, body[4:]...)
+			}
+		case "IEND":
+			pos = len(data)
+		default:
+			if !seenData {
+				shared.Write(chunk)
+			}
+		}
+	}
+	if !animated || len(frames) < 2 || len(ihdr) < 13 {
+		return nil, nil, nil
+	}
+
+	width, height := binary.BigEndian.Uint32(ihdr), binary.BigEndian.Uint32(ihdr[4:])
+	canvas := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	var images []image.Image
+	var delays []time.Duration
+	for _, frame := range frames {
+		c := frame.control
+		w, h := binary.BigEndian.Uint32(c[4:]), binary.BigEndian.Uint32(c[8:])
+		x, y := int(binary.BigEndian.Uint32(c[12:])), int(binary.BigEndian.Uint32(c[16:]))
+		delayNum, delayDen := binary.BigEndian.Uint16(c[20:]), binary.BigEndian.Uint16(c[22:])
+		dispose, blend := c[24], c[25]
+
+		header := append([]byte(nil), ihdr...)
+		binary.BigEndian.PutUint32(header, w)
+		binary.BigEndian.PutUint32(header[4:], h)
+		var stream bytes.Buffer
+		stream.Write(pngSignature)
+		writePNGChunk(&stream, "IHDR", header)
+		stream.Write(shared.Bytes())
+		writePNGChunk(&stream, "IDAT", frame.data)
+		writePNGChunk(&stream, "IEND", nil)
+		img, err := png.Decode(&stream)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		area := image.Rect(x, y, x+int(w), y+int(h))
+		var previous *image.RGBA
+		if dispose == 2 {
+			previous = cloneRGBA(canvas)
+		}
+		op := draw.Over
+		if blend == 0 {
+			op = draw.Src
+		}
+		draw.Draw(canvas, area, img, img.Bounds().Min, op)
+		images = append(images, cloneRGBA(canvas))
+
+		if delayDen == 0 {
+			delayDen = 100
+		}
+		delays = append(delays, frameDelay(time.Duration(delayNum)*time.Second/time.Duration(delayDen)))
+
+		switch dispose {
+		case 1:
+			draw.Draw(canvas, area, image.Transparent, image.Point{}, draw.Src)
+		case 2:
+			canvas = previous
+		}
+	}
+	return images, delays, nil
+}
+
+func writePNGChunk(w *bytes.Buffer, kind string, body []byte) {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(body)))
+	copy(header[4:], kind)
+	w.Write(header[:])
+	w.Write(body)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(body)
+	binary.Write(w, binary.BigEndian, crc.Sum32())
+}
+
+func frameDelay(delay time.Duration) time.Duration {
+	if delay < minFrameDelay {
+		return defaultFrameDelay
+	}
+	return delay
+}
+
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	c := image.NewRGBA(img.Bounds())
+	copy(c.Pix, img.Pix)
+	return c
+}
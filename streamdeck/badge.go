@@ -0,0 +1,124 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"angrysoft.ovh/angry-deck/page"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// DrawBadge draws the label text in a pill in a corner of the key: "top
+// right" (the default), "top left", "bottom left" or "bottom right". The
+// pill is at least as wide as it is high, so a single digit gets a circle.
+func (dd *DeckDevice) DrawBadge(img image.Image, corner string, label page.Label) (image.Image, error) {
+	result := image.NewRGBA(img.Bounds())
+	draw.Draw(result, result.Bounds(), img, img.Bounds().Min, draw.Src)
+	if label.Text == "" {
+		return result, nil
+	}
+
+	vertical, horizontal, err := parseCorner(corner)
+	if err != nil {
+		return result, err
+	}
+	face, err := dd.newFace(dd.fontChain(label.Font, label.FallbackFonts), label.FontSize)
+	if err != nil {
+		return result, err
+	}
+	background, err := page.ParseColor(label.Background)
+	if err != nil {
+		return result, err
+	}
+
+	metrics := face.Metrics()
+	width := font.MeasureString(face, label.Text).Ceil()
+	textHeight := (metrics.Ascent + metrics.Descent).Ceil()
+	pad := max(textHeight/6, 1)
+	height := textHeight + 2*pad
+	pill := image.Rect(0, 0, max(width+2*height/3, height), height)
+
+	margin := max(int(dd.Pixels)/36, 1)
+	bounds := result.Bounds()
+	offset := image.Point{X: bounds.Max.X - margin - pill.Dx(), Y: bounds.Min.Y + margin}
+	if horizontal == "left" {
+		offset.X = bounds.Min.X + margin
+	}
+	if vertical == "bottom" {
+		offset.Y = bounds.Max.Y - margin - pill.Dy()
+	}
+	pill = pill.Add(offset)
+	fillRoundedRect(result, pill, height/2, background)
+
+	col, err := textColor(label.FontColor, result, pill)
+	if err != nil {
+		return result, err
+	}
+	dot := fixed.Point26_6{
+		X: fixed.I(pill.Min.X) + (fixed.I(pill.Dx())-font.MeasureString(face, label.Text))/2,
+		Y: fixed.I(pill.Min.Y+pad) + metrics.Ascent,
+	}
+	drawString(result, face, image.NewUniform(col), dot, label.Text, true)
+	return result, nil
+}
+
+func parseCorner(corner string) (vertical, horizontal string, err error) {
+	if corner == "" {
+		return "top", "right", nil
+	}
+	vertical, horizontal, err = parseAlign(corner)
+	if err != nil || vertical == "center" || horizontal == "center" {
+		return "", "", fmt.Errorf("unknown badge corner: %s", corner)
+	}
+	return vertical, horizontal, nil
+}
+
+// fillRoundedRect fills r with anti-aliased corners of the given radius. The
+// alpha of c, as parsed from a hex colour, is not premultiplied.
+func fillRoundedRect(dst *image.RGBA, r image.Rectangle, radius int, c color.RGBA) {
+	area := r.Intersect(dst.Bounds())
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			coverage := roundedCoverage(r, radius, x, y)
+			if coverage == 0 {
+				continue
+			}
+			a := coverage * float64(c.A) / 255
+			under := dst.RGBAAt(x, y)
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(float64(c.R)*a + float64(under.R)*(1-a)),
+				G: uint8(float64(c.G)*a + float64(under.G)*(1-a)),
+				B: uint8(float64(c.B)*a + float64(under.B)*(1-a)),
+				A: uint8(255*a + float64(under.A)*(1-a)),
+			})
+		}
+	}
+}
+
+// roundedMask returns an alpha mask of r with rounded corners.
+func roundedMask(r image.Rectangle, radius int) *image.Alpha {
+	mask := image.NewAlpha(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			mask.SetAlpha(x, y, color.Alpha{A: uint8(roundedCoverage(r, radius, x, y)*255 + 0.5)})
+		}
+	}
+	return mask
+}
+
+// roundedCoverage returns how much of the pixel at x, y is inside r with
+// corners of the given radius, from 0 to 1.
+func roundedCoverage(r image.Rectangle, radius int, x, y int) float64 {
+	rad := float64(min(radius, r.Dx()/2, r.Dy()/2))
+	if rad <= 0 {
+		return 1
+	}
+	px, py := float64(x)+0.5, float64(y)+0.5
+	cx := min(max(px, float64(r.Min.X)+rad), float64(r.Max.X)-rad)
+	cy := min(max(py, float64(r.Min.Y)+rad), float64(r.Max.Y)-rad)
+	return min(max(rad-math.Hypot(px-cx, py-cy)+0.5, 0), 1)
+}
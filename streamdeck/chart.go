@@ -0,0 +1,101 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	imgDraw "image/draw"
+	"math"
+
+	"angrysoft.ovh/angry-deck/page"
+)
+
+// Chart is a history of values, from 0 to 1 and oldest first, drawn as a
+// sparkline or a bar chart across one or more keys in a row.
+type Chart struct {
+	Points []float64
+	Size   int
+	Style  string
+	Color  string
+	Label  page.Label
+	Span   int
+}
+
+// DrawChart renders the chart over the background with the button fill,
+// icon and labels as one image across the keys covered by the chart.
+func (dd *DeckDevice) DrawChart(dir string, background image.Image, icon page.Icon, chart Chart, labels ...page.Label) (image.Image, error) {
+	size := int(dd.Pixels)
+
+	canvas, err := iconCanvas(dd.SpanSize(image.Point{X: max(chart.Span, 1), Y: 1}), background, icon)
+	if err != nil {
+		return nil, err
+	}
+	err = dd.drawIcon(canvas, dir, icon, image.Rect(0, 0, size, size))
+	if err != nil {
+		return nil, err
+	}
+
+	colorHex := chart.Color
+	if colorHex == "" {
+		colorHex = defaultMeterColor
+	}
+	col, err := page.ParseColor(colorHex)
+	if err != nil {
+		return nil, err
+	}
+	area := canvas.Bounds().Inset(size / 12)
+	switch chart.Style {
+	case "bars":
+		drawBars(canvas, area, chart.Points, chart.Size, col)
+	default:
+		drawSparkline(canvas, area, chart.Points, chart.Size, col)
+	}
+
+	if chart.Label.Text != "" {
+		labels = append(labels, chart.Label)
+	}
+	return dd.drawLabels(canvas, labels)
+}
+
+// drawSparkline fills the area under the line with a translucent colour and
+// draws the line on top. The newest point is at the right edge and the line
+// fills from the right while the history is not full yet.
+func drawSparkline(img *image.RGBA, area image.Rectangle, points []float64, size int, col color.RGBA) {
+	if len(points) == 0 {
+		return
+	}
+	size = max(size, len(points), 2)
+	under := color.NRGBA{R: col.R, G: col.G, B: col.B, A: 96}
+	step := float64(area.Dx()-1) / float64(size-1)
+	first := size - len(points)
+
+	for x := area.Min.X; x < area.Max.X; x++ {
+		pos := float64(x-area.Min.X)/step - float64(first)
+		if pos < 0 {
+			continue
+		}
+		i := int(pos)
+		value := points[min(i, len(points)-1)]
+		if i+1 < len(points) {
+			value += (points[i+1] - value) * (pos - float64(i))
+		}
+		top := area.Max.Y - 1 - int(math.Round(value*float64(area.Dy()-1)))
+		imgDraw.Draw(img, image.Rect(x, top, x+1, area.Max.Y), image.NewUniform(under), image.Point{}, imgDraw.Over)
+		imgDraw.Draw(img, image.Rect(x, top, x+1, min(top+2, area.Max.Y)), image.NewUniform(col), image.Point{}, imgDraw.Src)
+	}
+}
+
+// drawBars draws one bar per point, newest at the right edge.
+func drawBars(img *image.RGBA, area image.Rectangle, points []float64, size int, col color.RGBA) {
+	size = max(size, len(points), 1)
+	width := float64(area.Dx()) / float64(size)
+	first := size - len(points)
+	for i, value := range points {
+		left := area.Min.X + int(float64(first+i)*width)
+		right := area.Min.X + int(float64(first+i+1)*width)
+		if right-left > 2 {
+			right--
+		}
+		top := area.Max.Y - int(math.Round(value*float64(area.Dy())))
+		imgDraw.Draw(img, image.Rect(left, top, right, area.Max.Y), image.NewUniform(col), image.Point{}, imgDraw.Src)
+	}
+}
@@ -0,0 +1,359 @@
+package streamdeck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"sync"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// bitmapFont reads colour glyphs stored as PNG images in the CBDT/CBLC
+// tables (Noto Color Emoji) or in the sbix table (Apple Color Emoji), which
+// the sfnt package does not render.
+type bitmapFont struct {
+	cblc    []byte
+	cbdt    []byte
+	sbix    []byte
+	strikes []bitmapStrike
+	lock    sync.Mutex
+	cache   map[bitmapKey]*bitmapGlyph
+}
+
+// bitmapStrike is one size of bitmaps. For CBLC it points at the index
+// sub-table array, for sbix at the strike itself.
+type bitmapStrike struct {
+	ppem     int
+	offset   uint32
+	count    uint32
+	first    sfnt.GlyphIndex
+	last     sfnt.GlyphIndex
+	isSbix   bool
+	numGlyph int
+}
+
+type bitmapKey struct {
+	glyph sfnt.GlyphIndex
+	ppem  int
+}
+
+// bitmapGlyph is a glyph image scaled to the requested size with its
+// placement relative to the dot, in pixels.
+type bitmapGlyph struct {
+	img     image.Image
+	offset  image.Point
+	advance fixed.Int26_6
+}
+
+// parseBitmapFont returns the colour bitmap tables of the first font in
+// data, or nil when it has none.
+func parseBitmapFont(data []byte, numGlyphs int) *bitmapFont {
+	tables := tableDirectory(data)
+	bf := &bitmapFont{
+		cblc:  tables["CBLC"],
+		cbdt:  tables["CBDT"],
+		sbix:  tables["sbix"],
+		cache: make(map[bitmapKey]*bitmapGlyph),
+	}
+	if bf.cblc != nil && bf.cbdt != nil {
+		bf.strikes = parseCBLCStrikes(bf.cblc)
+	} else if bf.sbix != nil {
+		bf.strikes = parseSbixStrikes(bf.sbix, numGlyphs)
+	}
+	if len(bf.strikes) == 0 {
+		return nil
+	}
+	return bf
+}
+
+// tableDirectory maps table tags to their data. For font collections the
+// first font is used.
+func tableDirectory(data []byte) map[string][]byte {
+	tables := make(map[string][]byte)
+	offset := uint32(0)
+	if len(data) >= 16 && string(data[:4]) == "ttcf" {
+		offset = u32(data, 12)
+	}
+	if int(offset)+12 > len(data) {
+		return tables
+	}
+	numTables := int(u16(data, int(offset)+4))
+	for i := 0; i < numTables; i++ {
+		record := int(offset) + 12 + i*16
+		if record+16 > len(data) {
+			break
+		}
+		start, length := u32(data, record+8), u32(data, record+12)
+		if uint64(start)+uint64(length) > uint64(len(data)) {
+			continue
+		}
+		tables[string(data[record:record+4])] = data[start : start+length]
+	}
+	return tables
+}
+
+func parseCBLCStrikes(cblc []byte) []bitmapStrike {
+	if len(cblc) < 8 {
+		return nil
+	}
+	numSizes := int(u32(cblc, 4))
+	var strikes []bitmapStrike
+	for i := 0; i < numSizes; i++ {
+		record := 8 + i*48
+		if record+48 > len(cblc) {
+			break
+		}
+		strikes = append(strikes, bitmapStrike{
+			offset: u32(cblc, record),
+			count:  u32(cblc, record+8),
+			first:  sfnt.GlyphIndex(u16(cblc, record+40)),
+			last:   sfnt.GlyphIndex(u16(cblc, record+42)),
+			ppem:   int(cblc[record+45]),
+		})
+	}
+	return strikes
+}
+
+func parseSbixStrikes(sbix []byte, numGlyphs int) []bitmapStrike {
+	if len(sbix) < 8 || numGlyphs <= 0 {
+		return nil
+	}
+	numStrikes := int(u32(sbix, 4))
+	var strikes []bitmapStrike
+	for i := 0; i < numStrikes; i++ {
+		if 8+i*4+4 > len(sbix) {
+			break
+		}
+		offset := u32(sbix, 8+i*4)
+		if int(offset)+4+(numGlyphs+1)*4 > len(sbix) {
+			continue
+		}
+		strikes = append(strikes, bitmapStrike{
+			ppem:     int(u16(sbix, int(offset))),
+			offset:   offset,
+			first:    0,
+			last:     sfnt.GlyphIndex(numGlyphs - 1),
+			isSbix:   true,
+			numGlyph: numGlyphs,
+		})
+	}
+	return strikes
+}
+
+// glyph returns the colour image of the glyph scaled to ppem pixels per em,
+// using the smallest strike at least that large.
+func (bf *bitmapFont) glyph(x sfnt.GlyphIndex, ppem int) (*bitmapGlyph, bool) {
+	bf.lock.Lock()
+	defer bf.lock.Unlock()
+	key := bitmapKey{glyph: x, ppem: ppem}
+	if g, exists := bf.cache[key]; exists {
+		return g, g != nil
+	}
+
+	var best *bitmapStrike
+	for i := range bf.strikes {
+		s := &bf.strikes[i]
+		if x < s.first || x > s.last {
+			continue
+		}
+		if best == nil || (best.ppem < ppem && s.ppem > best.ppem) || (s.ppem >= ppem && s.ppem < best.ppem) {
+			best = s
+		}
+	}
+	var g *bitmapGlyph
+	if best != nil {
+		if best.isSbix {
+			g = bf.sbixGlyph(best, x, ppem)
+		} else {
+			g = bf.cbdtGlyph(best, x, ppem)
+		}
+	}
+	bf.cache[key] = g
+	return g, g != nil
+}
+
+// cbdtGlyph looks the glyph up in the index sub-tables of the strike and
+// decodes its image.
+func (bf *bitmapFont) cbdtGlyph(s *bitmapStrike, x sfnt.GlyphIndex, ppem int) *bitmapGlyph {
+	cblc := bf.cblc
+	for i := uint32(0); i < s.count; i++ {
+		entry := int(s.offset + i*8)
+		if entry+8 > len(cblc) {
+			return nil
+		}
+		first, last := sfnt.GlyphIndex(u16(cblc, entry)), sfnt.GlyphIndex(u16(cblc, entry+2))
+		if x < first || x > last {
+			continue
+		}
+		sub := int(s.offset + u32(cblc, entry+4))
+		if sub+8 > len(cblc) {
+			return nil
+		}
+		indexFormat, imageFormat := u16(cblc, sub), u16(cblc, sub+2)
+		dataOffset := u32(cblc, sub+4)
+		n := int(x - first)
+
+		var start, end uint32
+		var metrics []byte
+		switch indexFormat {
+		case 1:
+			if sub+8+(n+2)*4 > len(cblc) {
+				return nil
+			}
+			start, end = u32(cblc, sub+8+n*4), u32(cblc, sub+8+(n+1)*4)
+		case 2:
+			if sub+20 > len(cblc) {
+				return nil
+			}
+			size := u32(cblc, sub+8)
+			metrics = cblc[sub+12 : sub+20]
+			start, end = uint32(n)*size, uint32(n+1)*size
+		case 3:
+			if sub+8+(n+2)*2 > len(cblc) {
+				return nil
+			}
+			start, end = uint32(u16(cblc, sub+8+n*2)), uint32(u16(cblc, sub+8+(n+1)*2))
+		case 4, 5:
+			var ok bool
+			start, end, metrics, ok = findSparseGlyph(cblc, sub, indexFormat, x)
+			if !ok {
+				return nil
+			}
+		default:
+			return nil
+		}
+		if end <= start || uint64(dataOffset)+uint64(end) > uint64(len(bf.cbdt)) {
+			return nil
+		}
+		return decodeCBDTGlyph(bf.cbdt[dataOffset+start:dataOffset+end], imageFormat, metrics, s.ppem, ppem)
+	}
+	return nil
+}
+
+// findSparseGlyph searches the glyph ID arrays of index formats 4 and 5.
+func findSparseGlyph(cblc []byte, sub int, format uint16, x sfnt.GlyphIndex) (start, end uint32, metrics []byte, ok bool) {
+	if format == 4 {
+		if sub+12 > len(cblc) {
+			return 0, 0, nil, false
+		}
+		numGlyphs := int(u32(cblc, sub+8))
+		for i := 0; i < numGlyphs; i++ {
+			pair := sub + 12 + i*4
+			if pair+8 > len(cblc) {
+				break
+			}
+			if sfnt.GlyphIndex(u16(cblc, pair)) == x {
+				return uint32(u16(cblc, pair+2)), uint32(u16(cblc, pair+6)), nil, true
+			}
+		}
+		return 0, 0, nil, false
+	}
+	if sub+24 > len(cblc) {
+		return 0, 0, nil, false
+	}
+	size := u32(cblc, sub+8)
+	metrics = cblc[sub+12 : sub+20]
+	numGlyphs := int(u32(cblc, sub+20))
+	for i := 0; i < numGlyphs; i++ {
+		if sub+24+i*2+2 > len(cblc) {
+			break
+		}
+		if sfnt.GlyphIndex(u16(cblc, sub+24+i*2)) == x {
+			return uint32(i) * size, uint32(i+1) * size, metrics, true
+		}
+	}
+	return 0, 0, nil, false
+}
+
+// decodeCBDTGlyph reads the PNG glyph formats 17, 18 and 19. metrics holds
+// the big glyph metrics of the index sub-table for format 19.
+func decodeCBDTGlyph(data []byte, format uint16, metrics []byte, strikePPEM, ppem int) *bitmapGlyph {
+	var bearingX, bearingY, advance int
+	var payload []byte
+	switch format {
+	case 17:
+		if len(data) < 9 {
+			return nil
+		}
+		bearingX, bearingY, advance = int(int8(data[2])), int(int8(data[3])), int(data[4])
+		payload = data[9:]
+	case 18:
+		if len(data) < 12 {
+			return nil
+		}
+		bearingX, bearingY, advance = int(int8(data[2])), int(int8(data[3])), int(data[4])
+		payload = data[12:]
+	case 19:
+		if len(data) < 4 || len(metrics) < 5 {
+			return nil
+		}
+		bearingX, bearingY, advance = int(int8(metrics[2])), int(int8(metrics[3])), int(metrics[4])
+		payload = data[4:]
+	default:
+		return nil
+	}
+	img, err := png.Decode(bytes.NewReader(payload))
+	if err != nil {
+		return nil
+	}
+	return scaleBitmapGlyph(img, image.Point{X: bearingX, Y: -bearingY}, advance, strikePPEM, ppem)
+}
+
+func (bf *bitmapFont) sbixGlyph(s *bitmapStrike, x sfnt.GlyphIndex, ppem int) *bitmapGlyph {
+	sbix := bf.sbix
+	record := int(s.offset) + 4 + int(x)*4
+	start := s.offset + u32(sbix, record)
+	end := s.offset + u32(sbix, record+4)
+	if end <= start+8 || int(end) > len(sbix) {
+		return nil
+	}
+	data := sbix[start:end]
+	if string(data[4:8]) != "png " {
+		return nil
+	}
+	img, err := png.Decode(bytes.NewReader(data[8:]))
+	if err != nil {
+		return nil
+	}
+	originX, originY := int(int16(u16(data, 0))), int(int16(u16(data, 2)))
+	// The origin is the bottom left corner of the image, y pointing up.
+	offset := image.Point{X: originX, Y: -(originY + img.Bounds().Dy())}
+	return scaleBitmapGlyph(img, offset, img.Bounds().Dx(), s.ppem, ppem)
+}
+
+func scaleBitmapGlyph(img image.Image, offset image.Point, advance int, strikePPEM, ppem int) *bitmapGlyph {
+	if strikePPEM <= 0 {
+		return nil
+	}
+	scale := float64(ppem) / float64(strikePPEM)
+	width := max(int(float64(img.Bounds().Dx())*scale+0.5), 1)
+	height := max(int(float64(img.Bounds().Dy())*scale+0.5), 1)
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return &bitmapGlyph{
+		img: scaled,
+		offset: image.Point{
+			X: int(float64(offset.X)*scale + 0.5),
+			Y: int(float64(offset.Y)*scale + 0.5),
+		},
+		advance: fixed.Int26_6(float64(advance) * scale * 64),
+	}
+}
+
+func u16(b []byte, i int) uint16 {
+	if i+2 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b[i:])
+}
+
+func u32(b []byte, i int) uint32 {
+	if i+4 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b[i:])
+}
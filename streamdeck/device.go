@@ -3,13 +3,18 @@ package streamdeck
 import (
 	"fmt"
 	"image"
-	"image/color"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
 
 	"angrysoft.ovh/angry-deck/page"
+	"angrysoft.ovh/angry-deck/widget"
 )
 
 const (
@@ -32,6 +37,17 @@ const (
 	USB_PID_STREAMDECK_XL_V2_MODULE    = "00ba"
 )
 
+// hidiocsFeature is the hidraw ioctl that sends a feature report, without
+// the report length, which goes in bits 16 and up.
+const hidiocsFeature = 0xc0004806
+
+// Protocol revisions. Rev1 devices, the original and the Mini, take BMP
+// images and rev2 devices take JPEG images.
+const (
+	rev1 = iota + 1
+	rev2
+)
+
 // Firmware command IDs.
 //
 //nolint:revive
@@ -64,7 +80,10 @@ type DeckDevice struct {
 	Pixels  uint
 	DPI     uint
 	Padding uint
+	// Gap is the space between two keys, in pixels of the key images.
+	Gap uint
 
+	revision            int
 	featureReportSize   int
 	firmwareOffset      int
 	keyStateOffset      int
@@ -81,6 +100,14 @@ type DeckDevice struct {
 
 	keyStateLength int
 	device         *os.File
+	writeLock      *sync.Mutex
+	keyImages      map[uint8]image.Image
+	fontName       string
+	fontDir        string
+	fallbackFonts  []string
+	iconDirs       []string
+	iconTheme      string
+	held           bool
 }
 
 func FindDevices() ([]DeckDevice, error) {
@@ -99,145 +126,21 @@ func FindDevices() ([]DeckDevice, error) {
 		fullPath := filepath.Join(sysUSBPath, file.Name())
 
 		// 2. Read VID and PID files inside the device folder
-		vid, _ := readFileValue(filepath.Join(fullPath, "idVendor"))
-		pid, _ := readFileValue(filepath.Join(fullPath, "idProduct"))
+		vid, _ := widget.ReadValue(filepath.Join(fullPath, "idVendor"))
+		pid, _ := widget.ReadValue(filepath.Join(fullPath, "idProduct"))
 
 		// Check if we found valid IDs
 		if vid != ELGATO_VID || pid == "" {
 			continue
 		}
-		var dev DeckDevice
-		path := ""
-		serial := ""
-
-		switch pid {
-		case USB_PID_STREAMDECK_ORIGINAL:
-			dev = DeckDevice{
-				SysFs:                fullPath,
-				ID:                   path,
-				Serial:               serial,
-				Columns:              5,
-				Rows:                 3,
-				Keys:                 15,
-				Pixels:               72,
-				DPI:                  124,
-				Padding:              16,
-				featureReportSize:    17,
-				firmwareOffset:       5,
-				keyStateOffset:       1,
-				translateKeyIndex:    translateRightToLeft,
-				imagePageSize:        7819,
-				imagePageHeaderSize:  16,
-				imagePageHeader:      rev1ImagePageHeader,
-				flipImage:            flipHorizontally,
-				toImageFormat:        toBMP,
-				getFirmwareCommand:   c_REV1_FIRMWARE,
-				resetCommand:         c_REV1_RESET,
-				setBrightnessCommand: c_REV1_BRIGHTNESS,
-			}
-		case USB_PID_STREAMDECK_MINI, USB_PID_STREAMDECK_MINI_MK2:
-			dev = DeckDevice{
-				SysFs:                fullPath,
-				ID:                   path,
-				Serial:               serial,
-				Columns:              3,
-				Rows:                 2,
-				Keys:                 6,
-				Pixels:               80,
-				DPI:                  138,
-				Padding:              16,
-				featureReportSize:    17,
-				firmwareOffset:       5,
-				keyStateOffset:       1,
-				translateKeyIndex:    identity,
-				imagePageSize:        1024,
-				imagePageHeaderSize:  16,
-				imagePageHeader:      miniImagePageHeader,
-				flipImage:            rotateCounterclockwise,
-				toImageFormat:        toBMP,
-				getFirmwareCommand:   c_REV1_FIRMWARE,
-				resetCommand:         c_REV1_RESET,
-				setBrightnessCommand: c_REV1_BRIGHTNESS,
-			}
-		case USB_PID_STREAMDECK_ORIGINAL_V2, USB_PID_STREAMDECK_MK2:
-			dev = DeckDevice{
-				SysFs:                fullPath,
-				ID:                   path,
-				Serial:               serial,
-				Columns:              5,
-				Rows:                 3,
-				Keys:                 15,
-				Pixels:               72,
-				DPI:                  124,
-				Padding:              16,
-				featureReportSize:    32,
-				firmwareOffset:       6,
-				keyStateOffset:       4,
-				translateKeyIndex:    identity,
-				imagePageSize:        1024,
-				imagePageHeaderSize:  8,
-				imagePageHeader:      rev2ImagePageHeader,
-				flipImage:            flipHorizontallyAndVertically,
-				toImageFormat:        toJPEG,
-				getFirmwareCommand:   c_REV2_FIRMWARE,
-				resetCommand:         c_REV2_RESET,
-				setBrightnessCommand: c_REV2_BRIGHTNESS,
-			}
-		case USB_PID_STREAMDECK_XL:
-			dev = DeckDevice{
-				SysFs:                fullPath,
-				ID:                   path,
-				Serial:               serial,
-				Columns:              8,
-				Rows:                 4,
-				Keys:                 32,
-				Pixels:               96,
-				DPI:                  166,
-				Padding:              16,
-				featureReportSize:    32,
-				firmwareOffset:       6,
-				keyStateOffset:       4,
-				translateKeyIndex:    identity,
-				imagePageSize:        1024,
-				imagePageHeaderSize:  8,
-				imagePageHeader:      rev2ImagePageHeader,
-				flipImage:            flipHorizontallyAndVertically,
-				toImageFormat:        toJPEG,
-				getFirmwareCommand:   c_REV2_FIRMWARE,
-				resetCommand:         c_REV2_RESET,
-				setBrightnessCommand: c_REV2_BRIGHTNESS,
-			}
-		case USB_PID_STREAMDECK_NEO:
-			dev = DeckDevice{
-				SysFs:                fullPath,
-				ID:                   path,
-				Serial:               serial,
-				Columns:              4,
-				Rows:                 3,
-				Keys:                 10,
-				Pixels:               96,
-				DPI:                  166,
-				Padding:              8,
-				featureReportSize:    32,
-				firmwareOffset:       6,
-				keyStateOffset:       4,
-				translateKeyIndex:    identity,
-				imagePageSize:        1024,
-				imagePageHeaderSize:  8,
-				imagePageHeader:      rev2ImagePageHeader,
-				flipImage:            flipHorizontallyAndVertically,
-				toImageFormat:        toJPEG,
-				getFirmwareCommand:   c_REV2_FIRMWARE,
-				resetCommand:         c_REV2_RESET,
-				setBrightnessCommand: c_REV2_BRIGHTNESS,
-			}
-		}
-		if dev.SysFs != "" {
-			manufacturer, _ := readFileValue(filepath.Join(fullPath, "manufacturer"))
-			product, _ := readFileValue(filepath.Join(fullPath, "product"))
+		dev, known := modelDevice(pid)
+		if known {
+			dev.SysFs = fullPath
+			manufacturer, _ := widget.ReadValue(filepath.Join(fullPath, "manufacturer"))
+			product, _ := widget.ReadValue(filepath.Join(fullPath, "product"))
 			dev.Manufacturer = manufacturer
 			dev.Product = product
-			serial, _ = readFileValue(filepath.Join(fullPath, "serial"))
+			serial, _ := widget.ReadValue(filepath.Join(fullPath, "serial"))
 			dev.Serial = serial
 			eventPath, err := findDevPath(fullPath)
 			dev.Path = eventPath
@@ -257,52 +160,190 @@ func FindDevices() ([]DeckDevice, error) {
 	return result, nil
 }
 
-func (dd *DeckDevice) SetButton(index uint8, dir string, label page.Label, icon page.Icon) {
-	fillColor := color.RGBA{0, 0, 0, 255}
-
-	if icon.Fill != "" {
-		col, err := parseHexColor(icon.Fill)
-		if err != nil {
-			fmt.Println("Error parsing fill color:", err)
-			return
-		}
-		fillColor = col
+// modelDevice returns the geometry and protocol of the model with the
+// product ID. Known reports whether the model is supported.
+func modelDevice(pid string) (dev DeckDevice, known bool) {
+	switch pid {
+	case USB_PID_STREAMDECK_ORIGINAL:
+		return DeckDevice{
+			Columns:              5,
+			Rows:                 3,
+			Keys:                 15,
+			Pixels:               72,
+			DPI:                  124,
+			Padding:              16,
+			Gap:                  16,
+			revision:             rev1,
+			featureReportSize:    17,
+			firmwareOffset:       5,
+			keyStateOffset:       1,
+			translateKeyIndex:    translateRightToLeft,
+			imagePageSize:        7819,
+			imagePageHeaderSize:  16,
+			imagePageHeader:      rev1ImagePageHeader,
+			flipImage:            flipHorizontally,
+			toImageFormat:        toBMP,
+			getFirmwareCommand:   c_REV1_FIRMWARE,
+			resetCommand:         c_REV1_RESET,
+			setBrightnessCommand: c_REV1_BRIGHTNESS,
+		}, true
+	case USB_PID_STREAMDECK_MINI, USB_PID_STREAMDECK_MINI_MK2:
+		return DeckDevice{
+			Columns:              3,
+			Rows:                 2,
+			Keys:                 6,
+			Pixels:               80,
+			DPI:                  138,
+			Padding:              16,
+			Gap:                  16,
+			revision:             rev1,
+			featureReportSize:    17,
+			firmwareOffset:       5,
+			keyStateOffset:       1,
+			translateKeyIndex:    identity,
+			imagePageSize:        1024,
+			imagePageHeaderSize:  16,
+			imagePageHeader:      miniImagePageHeader,
+			flipImage:            rotateCounterclockwise,
+			toImageFormat:        toBMP,
+			getFirmwareCommand:   c_REV1_FIRMWARE,
+			resetCommand:         c_REV1_RESET,
+			setBrightnessCommand: c_REV1_BRIGHTNESS,
+		}, true
+	case USB_PID_STREAMDECK_ORIGINAL_V2, USB_PID_STREAMDECK_MK2:
+		return DeckDevice{
+			Columns:              5,
+			Rows:                 3,
+			Keys:                 15,
+			Pixels:               72,
+			DPI:                  124,
+			Padding:              16,
+			Gap:                  16,
+			revision:             rev2,
+			featureReportSize:    32,
+			firmwareOffset:       6,
+			keyStateOffset:       4,
+			translateKeyIndex:    identity,
+			imagePageSize:        1024,
+			imagePageHeaderSize:  8,
+			imagePageHeader:      rev2ImagePageHeader,
+			flipImage:            flipHorizontallyAndVertically,
+			toImageFormat:        toJPEG,
+			getFirmwareCommand:   c_REV2_FIRMWARE,
+			resetCommand:         c_REV2_RESET,
+			setBrightnessCommand: c_REV2_BRIGHTNESS,
+		}, true
+	case USB_PID_STREAMDECK_XL:
+		return DeckDevice{
+			Columns:              8,
+			Rows:                 4,
+			Keys:                 32,
+			Pixels:               96,
+			DPI:                  166,
+			Padding:              16,
+			Gap:                  16,
+			revision:             rev2,
+			featureReportSize:    32,
+			firmwareOffset:       6,
+			keyStateOffset:       4,
+			translateKeyIndex:    identity,
+			imagePageSize:        1024,
+			imagePageHeaderSize:  8,
+			imagePageHeader:      rev2ImagePageHeader,
+			flipImage:            flipHorizontallyAndVertically,
+			toImageFormat:        toJPEG,
+			getFirmwareCommand:   c_REV2_FIRMWARE,
+			resetCommand:         c_REV2_RESET,
+			setBrightnessCommand: c_REV2_BRIGHTNESS,
+		}, true
+	case USB_PID_STREAMDECK_NEO:
+		return DeckDevice{
+			Columns:              4,
+			Rows:                 3,
+			Keys:                 10,
+			Pixels:               96,
+			DPI:                  166,
+			Padding:              8,
+			Gap:                  8,
+			revision:             rev2,
+			featureReportSize:    32,
+			firmwareOffset:       6,
+			keyStateOffset:       4,
+			translateKeyIndex:    identity,
+			imagePageSize:        1024,
+			imagePageHeaderSize:  8,
+			imagePageHeader:      rev2ImagePageHeader,
+			flipImage:            flipHorizontallyAndVertically,
+			toImageFormat:        toJPEG,
+			getFirmwareCommand:   c_REV2_FIRMWARE,
+			resetCommand:         c_REV2_RESET,
+			setBrightnessCommand: c_REV2_BRIGHTNESS,
+		}, true
 	}
+	return DeckDevice{}, false
+}
 
-	img := createNewRGBAImage(int(dd.Pixels), int(dd.Pixels), fillColor)
+// models maps the names of the supported models to their product IDs.
+var models = map[string]string{
+	"original":    USB_PID_STREAMDECK_ORIGINAL,
+	"original-v2": USB_PID_STREAMDECK_ORIGINAL_V2,
+	"mk2":         USB_PID_STREAMDECK_MK2,
+	"mini":        USB_PID_STREAMDECK_MINI,
+	"mini-mk2":    USB_PID_STREAMDECK_MINI_MK2,
+	"xl":          USB_PID_STREAMDECK_XL,
+	"neo":         USB_PID_STREAMDECK_NEO,
+}
 
-	if icon.File != "" {
-		iconPath := filepath.Join(dir, "images", icon.File)
-		iconImage, err := loadImageFromFile(iconPath)
+// ModelNames returns the names of the supported models in order.
+func ModelNames() []string {
+	return slices.Sorted(maps.Keys(models))
+}
 
-		if err != nil {
-			fmt.Println("Error loading icon image:", err)
-			return
-		}
-		img = iconImage
+// ModelDevice returns the named model without a device behind it, for
+// checking configurations against its keys and key size.
+func ModelDevice(name string) (DeckDevice, error) {
+	pid, exists := models[strings.ToLower(name)]
+	if !exists {
+		return DeckDevice{}, fmt.Errorf("unknown model %q, use one of: %s", name, strings.Join(ModelNames(), ", "))
 	}
-	if label.Text != "" {
-		textOnImg, err := dd.SetText(img, label.Text, "", label.FontSize, label.FontColor, image.Point{X: int(dd.Padding), Y: int(dd.Padding)})
-		if err != nil {
-			fmt.Println("Error setting text on image:", err)
-			return
-		}
-		img = textOnImg
+	dev, _ := modelDevice(pid)
+	dev.Product = name
+	dev.keyStateLength = int(dev.Columns * dev.Rows)
+	return dev, nil
+}
+
+func (dd *DeckDevice) SetButton(index uint8, dir string, icon page.Icon, labels ...page.Label) {
+	img, err := dd.RenderButton(dir, icon, labels...)
+	if err != nil {
+		fmt.Println("Error rendering button:", err)
+		return
 	}
-	err := dd.SetImage(index, img)
+	err = dd.SetImage(index, img)
 	if err != nil {
 		fmt.Println("Error setting image on button:", err)
 		return
 	}
 }
 
-// Helper to read a one-line file and trim whitespace
-func readFileValue(path string) (string, error) {
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return "", err
+// RenderButton draws the fill, the icon over it and the labels of a button
+// without sending it to the device.
+func (dd *DeckDevice) RenderButton(dir string, icon page.Icon, labels ...page.Label) (image.Image, error) {
+	return dd.RenderSpan(dir, image.Point{X: 1, Y: 1}, nil, icon, labels...)
+}
+
+// drawLabels draws every label with text on the image, in order.
+func (dd *DeckDevice) drawLabels(img image.Image, labels []page.Label) (image.Image, error) {
+	for _, label := range labels {
+		if label.Text == "" {
+			continue
+		}
+		textOnImg, err := dd.DrawLabel(img, label)
+		if err != nil {
+			return nil, fmt.Errorf("cannot set text on image: %w", err)
+		}
+		img = textOnImg
 	}
-	return strings.TrimSpace(string(content)), nil
+	return img, nil
 }
 
 func findDevPath(usbSysPath string) (string, error) {
@@ -346,6 +387,8 @@ func (dd *DeckDevice) OpenDeckDevice() error {
 		return err
 	}
 	dd.device = file
+	dd.writeLock = &sync.Mutex{}
+	dd.keyImages = make(map[uint8]image.Image)
 	return nil
 }
 
@@ -414,6 +457,38 @@ func (dd *DeckDevice) SetBrightness(percent uint8) error {
 	copy(report, dd.setBrightnessCommand)
 	report[len(report)-1] = percent
 
+	if dd.writeLock != nil {
+		dd.writeLock.Lock()
+		defer dd.writeLock.Unlock()
+	}
+	return dd.sendFeatureReport(report)
+}
+
+// sendFeatureReport sends the payload, padded to the feature report size,
+// with the HIDIOCSFEATURE ioctl of hidraw. The caller must hold
+// dd.writeLock.
+func (dd *DeckDevice) sendFeatureReport(payload []byte) error {
+	if dd.device == nil {
+		return fmt.Errorf("device not opened")
+	}
+	report := make([]byte, max(dd.featureReportSize, len(payload)))
+	copy(report, payload)
+	request := uintptr(hidiocsFeature | len(report)<<16)
+
+	conn, err := dd.device.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	err = conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(&report[0])))
+	})
+	if err != nil {
+		return err
+	}
+	if errno != 0 {
+		return fmt.Errorf("cannot send feature report: %w", errno)
+	}
 	return nil
 }
 
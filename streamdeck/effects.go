@@ -0,0 +1,50 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	imgDraw "image/draw"
+
+	"golang.org/x/image/draw"
+)
+
+// PressedEffect returns a copy of the key image with the effect applied.
+// Supported effects are darken, invert and inset.
+func PressedEffect(img image.Image, effect string) (image.Image, error) {
+	switch effect {
+	case "darken":
+		return mapPixels(img, func(c color.RGBA) color.RGBA {
+			return color.RGBA{R: c.R / 2, G: c.G / 2, B: c.B / 2, A: c.A}
+		}), nil
+	case "invert":
+		return mapPixels(img, func(c color.RGBA) color.RGBA {
+			return color.RGBA{R: c.A - c.R, G: c.A - c.G, B: c.A - c.B, A: c.A}
+		}), nil
+	case "inset":
+		return inset(img), nil
+	default:
+		return nil, fmt.Errorf("unknown pressed effect: %s", effect)
+	}
+}
+
+func mapPixels(img image.Image, fn func(color.RGBA) color.RGBA) *image.RGBA {
+	bounds := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	imgDraw.Draw(out, out.Bounds(), img, bounds.Min, imgDraw.Src)
+	for i := 0; i+3 < len(out.Pix); i += 4 {
+		c := fn(color.RGBA{R: out.Pix[i], G: out.Pix[i+1], B: out.Pix[i+2], A: out.Pix[i+3]})
+		out.Pix[i], out.Pix[i+1], out.Pix[i+2], out.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return out
+}
+
+// inset shrinks the image inside a black border so the key looks pushed in.
+func inset(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	imgDraw.Draw(out, out.Bounds(), image.NewUniform(color.RGBA{A: 255}), image.Point{}, imgDraw.Src)
+	border := max(bounds.Dx()/12, 2)
+	draw.ApproxBiLinear.Scale(out, out.Bounds().Inset(border), img, bounds, imgDraw.Src, nil)
+	return out
+}
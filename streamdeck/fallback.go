@@ -0,0 +1,240 @@
+package streamdeck
+
+import (
+	"image"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// SetFallbackFonts sets the fonts tried, in order, for characters missing
+// from the label font, after the fallback fonts of the label itself.
+func (dd *DeckDevice) SetFallbackFonts(names []string) {
+	dd.fallbackFonts = names
+}
+
+// fontChain returns the label font followed by its fallback fonts and the
+// deck fallback fonts, without duplicates.
+func (dd *DeckDevice) fontChain(name string, fallbacks []string) []*opentype.Font {
+	chain := []*opentype.Font{dd.loadFont(name)}
+	names := append(append([]string{}, fallbacks...), dd.fallbackFonts...)
+	for _, fallback := range names {
+		ttf := dd.loadFont(fallback)
+		if !containsFont(chain, ttf) {
+			chain = append(chain, ttf)
+		}
+	}
+	return chain
+}
+
+func containsFont(chain []*opentype.Font, ttf *opentype.Font) bool {
+	for _, f := range chain {
+		if f == ttf {
+			return true
+		}
+	}
+	return false
+}
+
+// fallbackFace draws each rune with the first font of the chain that has a
+// glyph for it. When none has, fontconfig is asked for a font covering the
+// rune and the embedded font is the last resort. Colour glyphs of bitmap
+// emoji fonts are available through ColorGlyph.
+type fallbackFace struct {
+	fonts  []*opentype.Font
+	faces  []font.Face
+	size   int
+	dpi    uint
+	buf    sfnt.Buffer
+	picked map[rune]int
+}
+
+func (dd *DeckDevice) newFace(chain []*opentype.Font, fontSize int) (*fallbackFace, error) {
+	if fontSize == 0 {
+		fontSize = defaultFontSize
+	}
+	f := &fallbackFace{
+		fonts:  chain,
+		faces:  make([]font.Face, len(chain)),
+		size:   fontSize,
+		dpi:    dd.DPI,
+		picked: make(map[rune]int),
+	}
+	// Fail early if the primary font cannot be used at this size.
+	if _, err := f.face(0); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *fallbackFace) face(i int) (font.Face, error) {
+	if f.faces[i] == nil {
+		face, err := fonts.face(f.fonts[i], f.size, f.dpi)
+		if err != nil {
+			return nil, err
+		}
+		f.faces[i] = face
+	}
+	return f.faces[i], nil
+}
+
+// pick returns the index of the font used for r.
+func (f *fallbackFace) pick(r rune) int {
+	if i, exists := f.picked[r]; exists {
+		return i
+	}
+	i := f.find(r)
+	if i < 0 {
+		extra := fonts.forRune(r)
+		if extra == nil || !f.hasGlyph(extra, r) {
+			extra = fonts.embedded()
+		}
+		if f.hasGlyph(extra, r) {
+			i = f.add(extra)
+		} else {
+			i = 0
+		}
+	}
+	f.picked[r] = i
+	return i
+}
+
+func (f *fallbackFace) find(r rune) int {
+	for i, ttf := range f.fonts {
+		if f.hasGlyph(ttf, r) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *fallbackFace) add(ttf *opentype.Font) int {
+	for i, existing := range f.fonts {
+		if existing == ttf {
+			return i
+		}
+	}
+	f.fonts = append(f.fonts, ttf)
+	f.faces = append(f.faces, nil)
+	return len(f.fonts) - 1
+}
+
+func (f *fallbackFace) hasGlyph(ttf *opentype.Font, r rune) bool {
+	x, err := ttf.GlyphIndex(&f.buf, r)
+	return err == nil && x != 0
+}
+
+// bitmapGlyph returns the colour glyph for r if its font is a bitmap font.
+func (f *fallbackFace) bitmapGlyph(r rune) (*bitmapGlyph, bool) {
+	ttf := f.fonts[f.pick(r)]
+	bf := fonts.bitmapFor(ttf)
+	if bf == nil {
+		return nil, false
+	}
+	x, err := ttf.GlyphIndex(&f.buf, r)
+	if err != nil || x == 0 {
+		return nil, false
+	}
+	ppem := int(float64(f.size)*float64(f.dpi)/72 + 0.5)
+	return bf.glyph(x, ppem)
+}
+
+// ColorGlyph returns the colour image of r and where to draw it, for fonts
+// that store glyphs as images.
+func (f *fallbackFace) ColorGlyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, fixed.Int26_6, bool) {
+	g, ok := f.bitmapGlyph(r)
+	if !ok {
+		return image.Rectangle{}, nil, 0, false
+	}
+	min := image.Point{X: dot.X.Round(), Y: dot.Y.Round()}.Add(g.offset)
+	return g.img.Bounds().Add(min), g.img, g.advance, true
+}
+
+func (f *fallbackFace) Close() error {
+	return nil
+}
+
+// Glyph returns the silhouette of colour glyphs, so shadows and outlines
+// follow their shape.
+func (f *fallbackFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	if dr, img, advance, ok := f.ColorGlyph(dot, r); ok {
+		return dr, img, image.Point{}, advance, true
+	}
+	face, err := f.face(f.pick(r))
+	if err != nil {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	return face.Glyph(dot, r)
+}
+
+func (f *fallbackFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	if g, ok := f.bitmapGlyph(r); ok {
+		b := g.img.Bounds().Add(g.offset)
+		return fixed.R(b.Min.X, b.Min.Y, b.Max.X, b.Max.Y), g.advance, true
+	}
+	face, err := f.face(f.pick(r))
+	if err != nil {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+	return face.GlyphBounds(r)
+}
+
+func (f *fallbackFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	if g, ok := f.bitmapGlyph(r); ok {
+		return g.advance, true
+	}
+	face, err := f.face(f.pick(r))
+	if err != nil {
+		return 0, false
+	}
+	return face.GlyphAdvance(r)
+}
+
+func (f *fallbackFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	i := f.pick(r0)
+	if i != f.pick(r1) {
+		return 0
+	}
+	face, err := f.face(i)
+	if err != nil {
+		return 0
+	}
+	return face.Kern(r0, r1)
+}
+
+func (f *fallbackFace) Metrics() font.Metrics {
+	face, err := f.face(0)
+	if err != nil {
+		return font.Metrics{}
+	}
+	return face.Metrics()
+}
+
+// drawString draws text like font.Drawer. With colorGlyphs the images of
+// colour glyphs are drawn instead of their silhouette in src.
+func drawString(dst draw.Image, face font.Face, src image.Image, dot fixed.Point26_6, text string, colorGlyphs bool) {
+	colorFace, _ := face.(*fallbackFace)
+	prev := rune(-1)
+	for _, r := range text {
+		if prev >= 0 {
+			dot.X += face.Kern(prev, r)
+		}
+		prev = r
+		if colorGlyphs && colorFace != nil {
+			if dr, img, advance, ok := colorFace.ColorGlyph(dot, r); ok {
+				draw.Draw(dst, dr, img, img.Bounds().Min, draw.Over)
+				dot.X += advance
+				continue
+			}
+		}
+		dr, mask, maskp, advance, ok := face.Glyph(dot, r)
+		if !ok {
+			continue
+		}
+		draw.DrawMask(dst, dr, src, image.Point{}, mask, maskp, draw.Over)
+		dot.X += advance
+	}
+}
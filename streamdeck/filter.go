@@ -0,0 +1,134 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"angrysoft.ovh/angry-deck/page"
+	"golang.org/x/image/draw"
+)
+
+// applyFilters runs the filter pipeline of an icon over its scaled image.
+// Blurred images grow by the blur radius, up to the box the icon is drawn
+// in, so the blur is not cut at the edges of the image.
+func applyFilters(img *image.RGBA, filters []page.Filter, box image.Rectangle) (*image.RGBA, error) {
+	for _, filter := range filters {
+		if filter.Grayscale > 0 {
+			amount := min(filter.Grayscale, 1)
+			mapColors(img, func(r, g, b float64) (float64, float64, float64) {
+				y := 0.2126*r + 0.7152*g + 0.0722*b
+				return r + (y-r)*amount, g + (y-g)*amount, b + (y-b)*amount
+			})
+		}
+		if filter.Brightness != 0 {
+			offset := filter.Brightness
+			mapColors(img, func(r, g, b float64) (float64, float64, float64) {
+				return r + offset, g + offset, b + offset
+			})
+		}
+		if filter.Contrast != 0 {
+			factor := max(1+filter.Contrast, 0)
+			mapColors(img, func(r, g, b float64) (float64, float64, float64) {
+				return (r-0.5)*factor + 0.5, (g-0.5)*factor + 0.5, (b-0.5)*factor + 0.5
+			})
+		}
+		if filter.Tint != "" {
+			tint, err := page.ParseColor(filter.Tint)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse tint color: %w", err)
+			}
+			strength := float64(tint.A) / 255
+			tr, tg, tb := float64(tint.R)/255, float64(tint.G)/255, float64(tint.B)/255
+			mapColors(img, func(r, g, b float64) (float64, float64, float64) {
+				return r + (r*tr-r)*strength, g + (g*tg-g)*strength, b + (b*tb-b)*strength
+			})
+		}
+		if filter.Invert {
+			mapColors(img, func(r, g, b float64) (float64, float64, float64) {
+				return 1 - r, 1 - g, 1 - b
+			})
+		}
+		if filter.Blur > 0 {
+			img = blurImage(img, filter.Blur, box)
+		}
+		if filter.Opacity != nil {
+			fadeImage(img, min(max(*filter.Opacity, 0), 1))
+		}
+	}
+	return img, nil
+}
+
+// mapColors replaces the colour of every pixel, with channels from 0 to 1
+// and not premultiplied. Results are clamped and the alpha is kept.
+func mapColors(img *image.RGBA, f func(r, g, b float64) (float64, float64, float64)) {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			if c.A == 0 {
+				continue
+			}
+			a := float64(c.A) / 255
+			r, g, bl := f(float64(c.R)/255/a, float64(c.G)/255/a, float64(c.B)/255/a)
+			img.SetRGBA(x, y, color.RGBA{
+				R: premultiplied(r, a),
+				G: premultiplied(g, a),
+				B: premultiplied(bl, a),
+				A: c.A,
+			})
+		}
+	}
+}
+
+func premultiplied(v, a float64) uint8 {
+	return uint8(min(max(v, 0), 1)*a*255 + 0.5)
+}
+
+// fadeImage multiplies the alpha of every pixel by opacity.
+func fadeImage(img *image.RGBA, opacity float64) {
+	for i := range img.Pix {
+		img.Pix[i] = uint8(float64(img.Pix[i])*opacity + 0.5)
+	}
+}
+
+// blurImage approximates a gaussian blur of the radius with three box blurs
+// in each direction.
+func blurImage(img *image.RGBA, radius int, box image.Rectangle) *image.RGBA {
+	bounds := img.Bounds().Inset(-radius).Intersect(box).Union(img.Bounds())
+	blurred := image.NewRGBA(bounds)
+	draw.Draw(blurred, img.Bounds(), img, img.Bounds().Min, draw.Src)
+	boxRadius := max(int(math.Round(float64(radius)/math.Sqrt(3))), 1)
+	tmp := image.NewRGBA(bounds)
+	for range 3 {
+		boxBlur(tmp, blurred, boxRadius, 4, bounds.Dx()*4, bounds.Dx(), bounds.Dy())
+		boxBlur(blurred, tmp, boxRadius, bounds.Dx()*4, 4, bounds.Dy(), bounds.Dx())
+	}
+	return blurred
+}
+
+// boxBlur averages the pixels of src along lines into dst. step is the
+// distance between pixels of a line in Pix, stride the one between lines.
+// Pixels outside the image count as transparent.
+func boxBlur(dst, src *image.RGBA, radius, step, stride, length, lines int) {
+	width := float64(2*radius + 1)
+	for line := range lines {
+		start := line * stride
+		for c := range 4 {
+			sum := 0
+			for i := 0; i < min(radius, length); i++ {
+				sum += int(src.Pix[start+i*step+c])
+			}
+			for i := range length {
+				if i+radius < length {
+					sum += int(src.Pix[start+(i+radius)*step+c])
+				}
+				if i-radius-1 >= 0 {
+					sum -= int(src.Pix[start+(i-radius-1)*step+c])
+				}
+				dst.Pix[start+i*step+c] = uint8(float64(sum)/width + 0.5)
+			}
+		}
+	}
+}
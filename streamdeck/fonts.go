@@ -0,0 +1,300 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// defaultFontFamily is looked up with fontconfig when neither the label nor
+// the deck settings name a font.
+const defaultFontFamily = "sans-serif"
+
+type faceKey struct {
+	font *opentype.Font
+	size int
+	dpi  uint
+}
+
+// fontCache keeps parsed fonts by name and faces by font, size and DPI, so
+// a font file is read only once.
+type fontCache struct {
+	lock     sync.Mutex
+	fonts    map[string]*opentype.Font
+	faces    map[faceKey]font.Face
+	bitmaps  map[*opentype.Font]*bitmapFont
+	runes    map[rune]*opentype.Font
+	fallback *opentype.Font
+}
+
+var fonts = &fontCache{
+	fonts:   make(map[string]*opentype.Font),
+	faces:   make(map[faceKey]font.Face),
+	bitmaps: make(map[*opentype.Font]*bitmapFont),
+	runes:   make(map[rune]*opentype.Font),
+}
+
+// ResetCaches forgets the parsed fonts and icon themes and the resolved
+// theme icons, so changed files are read again.
+func ResetCaches() {
+	fonts.reset()
+	iconThemes.reset()
+}
+
+func (c *fontCache) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.fonts = make(map[string]*opentype.Font)
+	c.faces = make(map[faceKey]font.Face)
+	c.bitmaps = make(map[*opentype.Font]*bitmapFont)
+	c.runes = make(map[rune]*opentype.Font)
+}
+
+// SetDefaultFont sets the font used by labels without their own font. Like
+// label fonts it is a font file or a family name, and relative paths are
+// resolved against dir.
+func (dd *DeckDevice) SetDefaultFont(name string, dir string) {
+	dd.fontName = name
+	dd.fontDir = dir
+}
+
+// loadFont returns the named font, the default font when name is empty, or
+// the embedded Go font when the font cannot be loaded.
+func (dd *DeckDevice) loadFont(name string) *opentype.Font {
+	if name == "" {
+		name = dd.fontName
+	}
+	if name == "" {
+		name = defaultFontFamily
+	}
+	if !filepath.IsAbs(name) && isFontFile(name) && dd.fontDir != "" {
+		name = filepath.Join(dd.fontDir, name)
+	}
+	return fonts.load(name)
+}
+
+func (c *fontCache) load(name string) *opentype.Font {
+	c.lock.Lock()
+	ttf, exists := c.fonts[name]
+	c.lock.Unlock()
+	if exists {
+		return ttf
+	}
+
+	// fc-match and reading the file are slow, so they run without the lock
+	// and the font loaded first is kept.
+	ttf, bitmaps, err := parseFontFile(name)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if cached, exists := c.fonts[name]; exists {
+		return cached
+	}
+	if err != nil {
+		log.Printf("Cannot load font %q, using the embedded font: %v\n", name, err)
+		ttf = c.fallbackFont()
+	} else if bitmaps != nil {
+		c.bitmaps[ttf] = bitmaps
+	}
+	c.fonts[name] = ttf
+	return ttf
+}
+
+// forRune asks fontconfig for a font that has a glyph for r. It returns nil
+// when there is none.
+func (c *fontCache) forRune(r rune) *opentype.Font {
+	c.lock.Lock()
+	ttf, exists := c.runes[r]
+	c.lock.Unlock()
+	if exists {
+		return ttf
+	}
+
+	// As in load, fc-match and the font file are read without the lock.
+	var bitmaps *bitmapFont
+	path, err := matchFont(fmt.Sprintf(":charset=%x", r))
+	if err == nil {
+		c.lock.Lock()
+		ttf = c.fonts[path]
+		c.lock.Unlock()
+		if ttf == nil {
+			ttf, bitmaps, err = parseFontFile(path)
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if cached, exists := c.runes[r]; exists {
+		return cached
+	}
+	if err == nil {
+		if cached, exists := c.fonts[path]; exists {
+			ttf = cached
+		} else {
+			c.fonts[path] = ttf
+			if bitmaps != nil {
+				c.bitmaps[ttf] = bitmaps
+			}
+		}
+	}
+	c.runes[r] = ttf
+	return ttf
+}
+
+func (c *fontCache) bitmapFor(ttf *opentype.Font) *bitmapFont {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.bitmaps[ttf]
+}
+
+// embedded returns the embedded Go font.
+func (c *fontCache) embedded() *opentype.Font {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.fallbackFont()
+}
+
+// fallbackFont returns the embedded Go font. The caller must hold c.lock.
+func (c *fontCache) fallbackFont() *opentype.Font {
+	if c.fallback == nil {
+		// The embedded font is known to be valid.
+		c.fallback, _ = opentype.Parse(goregular.TTF)
+	}
+	return c.fallback
+}
+
+// face returns a shared face for the font at the given size. Faces are not
+// safe for concurrent use, so the shared one serialises its callers.
+func (c *fontCache) face(ttf *opentype.Font, size int, dpi uint) (font.Face, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := faceKey{font: ttf, size: size, dpi: dpi}
+	if face, exists := c.faces[key]; exists {
+		return face, nil
+	}
+	face, err := opentype.NewFace(ttf, &opentype.FaceOptions{
+		Size:    float64(size),
+		DPI:     float64(dpi),
+		Hinting: font.HintingNone,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.faces[key] = &lockedFace{face: face}
+	return c.faces[key], nil
+}
+
+// parseFontFile loads a font file, or the file fontconfig picks for a family
+// name, and its bitmap glyphs if it has any. The first font of a collection
+// is used.
+func parseFontFile(name string) (*opentype.Font, *bitmapFont, error) {
+	path := name
+	if !isFontFile(name) {
+		var err error
+		path, err = matchFont(name)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read font file: %w", err)
+	}
+	collection, err := opentype.ParseCollection(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	ttf, err := collection.Font(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ttf, parseBitmapFont(data, ttf.NumGlyphs()), nil
+}
+
+// matchFont asks fontconfig for the file of a font family, for example
+// "DejaVu Sans" or "monospace:bold".
+func matchFont(family string) (string, error) {
+	output, err := exec.Command("fc-match", "--format=%{file}", family).Output()
+	if err != nil {
+		return "", fmt.Errorf("fc-match %q failed: %w", family, err)
+	}
+	path := strings.TrimSpace(string(output))
+	if path == "" {
+		return "", fmt.Errorf("fc-match found no font for %q", family)
+	}
+	return path, nil
+}
+
+func isFontFile(name string) bool {
+	if strings.ContainsRune(name, os.PathSeparator) {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".ttf", ".otf", ".ttc", ".otc":
+		return true
+	}
+	return false
+}
+
+// lockedFace makes a font.Face safe to share between goroutines.
+type lockedFace struct {
+	lock sync.Mutex
+	face font.Face
+}
+
+func (f *lockedFace) Close() error {
+	return nil
+}
+
+func (f *lockedFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	dr, mask, maskp, advance, ok := f.face.Glyph(dot, r)
+	if !ok || mask == nil {
+		return dr, mask, maskp, advance, ok
+	}
+	// The mask is reused by the next call, so hand out a copy.
+	return dr, copyMask(mask), maskp, advance, ok
+}
+
+func (f *lockedFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.face.GlyphBounds(r)
+}
+
+func (f *lockedFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.face.GlyphAdvance(r)
+}
+
+func (f *lockedFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.face.Kern(r0, r1)
+}
+
+func (f *lockedFace) Metrics() font.Metrics {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.face.Metrics()
+}
+
+func copyMask(mask image.Image) image.Image {
+	if alpha, ok := mask.(*image.Alpha); ok {
+		c := *alpha
+		c.Pix = append([]byte(nil), alpha.Pix...)
+		return &c
+	}
+	return mask
+}
@@ -0,0 +1,107 @@
+package streamdeck
+
+import (
+	"image"
+	imgDraw "image/draw"
+
+	"angrysoft.ovh/angry-deck/page"
+)
+
+// The key grid is drawn as one image in which keys are Pixels wide and Gap
+// apart, the physical gap between keys, so images spread across several
+// keys line up like they would on a single screen.
+
+// keyOrigin returns the top left corner of the key in the grid image.
+func (dd *DeckDevice) keyOrigin(index uint8) image.Point {
+	step := int(dd.Pixels + dd.Gap)
+	col, row := int(index%dd.Columns), int(index/dd.Columns)
+	return image.Point{X: col * step, Y: row * step}
+}
+
+// SpanSize returns the size of an area of span.X columns and span.Y rows of
+// keys, with the gaps between them.
+func (dd *DeckDevice) SpanSize(span image.Point) image.Point {
+	return image.Point{
+		X: span.X*int(dd.Pixels) + (span.X-1)*int(dd.Gap),
+		Y: span.Y*int(dd.Pixels) + (span.Y-1)*int(dd.Gap),
+	}
+}
+
+// ClipSpan limits the span of a button at the key index to the grid.
+func (dd *DeckDevice) ClipSpan(index uint8, span image.Point) image.Point {
+	col, row := int(index%dd.Columns), int(index/dd.Columns)
+	return image.Point{
+		X: min(max(span.X, 1), int(dd.Columns)-col),
+		Y: min(max(span.Y, 1), int(dd.Rows)-row),
+	}
+}
+
+// SpanKeys returns the keys covered by a span starting at the key index,
+// row by row.
+func (dd *DeckDevice) SpanKeys(index uint8, span image.Point) []uint8 {
+	var keys []uint8
+	for row := 0; row < span.Y; row++ {
+		for col := 0; col < span.X; col++ {
+			keys = append(keys, index+uint8(row)*dd.Columns+uint8(col))
+		}
+	}
+	return keys
+}
+
+// RenderBackground draws a page background across the whole key grid. The
+// image covers the grid unless the icon sets another scale mode.
+func (dd *DeckDevice) RenderBackground(dir string, icon page.Icon) (image.Image, error) {
+	size := dd.SpanSize(image.Point{X: int(dd.Columns), Y: int(dd.Rows)})
+	grid, err := iconCanvas(size, nil, icon)
+	if err != nil {
+		return nil, err
+	}
+	if icon.Scale == "" {
+		icon.Scale = "fill"
+	}
+	err = dd.drawIcon(grid, dir, icon, grid.Bounds())
+	if err != nil {
+		return nil, err
+	}
+	return grid, nil
+}
+
+// GridArea returns the part of the grid image under a span starting at the
+// key index.
+func (dd *DeckDevice) GridArea(grid image.Image, index uint8, span image.Point) image.Image {
+	origin := dd.keyOrigin(index)
+	area := image.Rectangle{Min: origin, Max: origin.Add(dd.SpanSize(span))}
+	img := image.NewRGBA(image.Rectangle{Max: area.Size()})
+	imgDraw.Draw(img, img.Bounds(), grid, area.Min, imgDraw.Src)
+	return img
+}
+
+// SliceSpan cuts the image of a span into the images of its keys, row by
+// row, leaving out the gaps.
+func (dd *DeckDevice) SliceSpan(img image.Image, span image.Point) []image.Image {
+	var keys []image.Image
+	step := int(dd.Pixels + dd.Gap)
+	for row := 0; row < span.Y; row++ {
+		for col := 0; col < span.X; col++ {
+			key := image.NewRGBA(image.Rect(0, 0, int(dd.Pixels), int(dd.Pixels)))
+			origin := img.Bounds().Min.Add(image.Point{X: col * step, Y: row * step})
+			imgDraw.Draw(key, key.Bounds(), img, origin, imgDraw.Src)
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// RenderSpan draws a button covering a span of keys. The button fill is
+// drawn over the background, which may be nil and is the size of the span.
+func (dd *DeckDevice) RenderSpan(dir string, span image.Point, background image.Image, icon page.Icon, labels ...page.Label) (image.Image, error) {
+	img, err := iconCanvas(dd.SpanSize(span), background, icon)
+	if err != nil {
+		return nil, err
+	}
+	err = dd.drawIcon(img, dir, icon, img.Bounds())
+	if err != nil {
+		return nil, err
+	}
+	return dd.drawLabels(img, labels)
+}
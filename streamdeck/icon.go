@@ -0,0 +1,198 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	imgDraw "image/draw"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"angrysoft.ovh/angry-deck/page"
+	"golang.org/x/image/draw"
+)
+
+// iconCanvas returns an image of the given size with the fill of the icon
+// over the background, which may be nil for black.
+func iconCanvas(size image.Point, background image.Image, icon page.Icon) (*image.RGBA, error) {
+	canvas := createNewRGBAImage(size.X, size.Y, black).(*image.RGBA)
+	if background != nil {
+		imgDraw.Draw(canvas, canvas.Bounds(), background, background.Bounds().Min, imgDraw.Src)
+	}
+	err := fillArea(canvas, canvas.Bounds(), icon.Fill)
+	if err != nil {
+		return nil, err
+	}
+	return canvas, nil
+}
+
+// drawIcon composites the icon image over dst inside area, less the icon
+// padding, scaled according to the icon scale mode, and then the layers of
+// the icon. SVG icons are rendered at the scaled size.
+func (dd *DeckDevice) drawIcon(dst *image.RGBA, dir string, icon page.Icon, area image.Rectangle) error {
+	box := area.Inset(icon.Padding)
+	if (icon.File != "" || icon.Theme != "") && !box.Empty() {
+		path, err := dd.iconPath(dir, icon, max(box.Dx(), box.Dy()))
+		if err != nil {
+			return err
+		}
+		scaled, err := loadScaledIcon(path, icon.Scale, box)
+		if err != nil {
+			return err
+		}
+		err = composeIcon(dst, icon, box, scaled)
+		if err != nil {
+			return err
+		}
+	}
+	return dd.drawLayers(dst, dir, icon.Layers, area)
+}
+
+// composeIcon draws the scaled icon image over dst, cut at the box, tinted,
+// filtered and with rounded corners when the icon has a radius. Only
+// monochrome icons are tinted, so a theme tint leaves colour icons alone.
+func composeIcon(dst *image.RGBA, icon page.Icon, box image.Rectangle, scaled *image.RGBA) error {
+	if icon.Tint != "" && monochrome(scaled) {
+		tint, err := page.ParseColor(icon.Tint)
+		if err != nil {
+			return fmt.Errorf("cannot parse tint color: %w", err)
+		}
+		tintImage(scaled, tint)
+	}
+	scaled, err := applyFilters(scaled, icon.Filters, box)
+	if err != nil {
+		return err
+	}
+
+	visible := scaled.Bounds().Intersect(box)
+	var mask image.Image
+	if icon.Radius > 0 {
+		mask = roundedMask(visible, icon.Radius)
+	}
+	imgDraw.DrawMask(dst, visible, scaled, visible.Min, mask, visible.Min, imgDraw.Over)
+	return nil
+}
+
+// SetIconDirs sets the directories searched for icon files after the images
+// directory of the configuration. Relative paths are resolved against dir.
+func (dd *DeckDevice) SetIconDirs(dirs []string, dir string) {
+	dd.iconDirs = nil
+	for _, iconDir := range dirs {
+		if !filepath.IsAbs(iconDir) {
+			iconDir = filepath.Join(dir, iconDir)
+		}
+		dd.iconDirs = append(dd.iconDirs, iconDir)
+	}
+}
+
+// iconPath returns the file of the icon. Theme icons are looked up in the
+// icon theme at the given size. Absolute files are used as they are and
+// relative ones are searched in the images directory under dir and then in
+// the icon directories.
+func (dd *DeckDevice) iconPath(dir string, icon page.Icon, size int) (string, error) {
+	if icon.Theme != "" {
+		theme := dd.iconTheme
+		if theme == "" {
+			theme = defaultIconTheme()
+		}
+		path := iconThemes.find(theme, icon.Theme, size)
+		if path == "" {
+			return "", fmt.Errorf("icon %q not found in icon theme %s", icon.Theme, theme)
+		}
+		return path, nil
+	}
+	if filepath.IsAbs(icon.File) {
+		return icon.File, nil
+	}
+	candidates := []string{filepath.Join(dir, "images", icon.File)}
+	for _, iconDir := range dd.iconDirs {
+		candidates = append(candidates, filepath.Join(iconDir, icon.File))
+	}
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("icon %s not found in %s", icon.File, strings.Join(candidates, ", "))
+}
+
+// FindIcon returns an error when the file or theme icon of the icon cannot
+// be found, looking it up as drawing the icon does.
+func (dd *DeckDevice) FindIcon(dir string, icon page.Icon) error {
+	path, err := dd.iconPath(dir, icon, int(dd.Pixels))
+	if err != nil {
+		return err
+	}
+	_, err = os.Stat(path)
+	return err
+}
+
+// loadScaledIcon loads the icon file and returns it scaled into box, with
+// the bounds of the image where it is drawn.
+func loadScaledIcon(path string, mode string, box image.Rectangle) (*image.RGBA, error) {
+	if isSVG(path) {
+		svg, err := loadSVG(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load icon image: %w", err)
+		}
+		target, err := scaleRect(mode, svgSize(svg), box)
+		if err != nil {
+			return nil, err
+		}
+		return renderSVG(svg, target), nil
+	}
+
+	src, err := loadImageFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load icon image: %w", err)
+	}
+	return scaleIcon(src, mode, box)
+}
+
+// scaleIcon scales a bitmap icon into box according to the scale mode.
+func scaleIcon(src image.Image, mode string, box image.Rectangle) (*image.RGBA, error) {
+	target, err := scaleRect(mode, src.Bounds().Size(), box)
+	if err != nil {
+		return nil, err
+	}
+	scaled := image.NewRGBA(target)
+	if target.Size() == src.Bounds().Size() {
+		imgDraw.Draw(scaled, target, src, src.Bounds().Min, imgDraw.Src)
+	} else {
+		draw.BiLinear.Scale(scaled, target, src, src.Bounds(), draw.Src, nil)
+	}
+	return scaled, nil
+}
+
+// scaleRect returns where an image of the given size is drawn in box:
+//
+//   - fit scales it to fit inside the box, keeping the aspect ratio (default)
+//   - fill scales it to cover the box, keeping the aspect ratio
+//   - stretch scales it to the box
+//   - center keeps its size
+//
+// The image is centred in the box and cut at its edges.
+func scaleRect(mode string, size image.Point, box image.Rectangle) (image.Rectangle, error) {
+	if size.X <= 0 || size.Y <= 0 {
+		return box, nil
+	}
+	scaleX := float64(box.Dx()) / float64(size.X)
+	scaleY := float64(box.Dy()) / float64(size.Y)
+	var scale float64
+	switch mode {
+	case "", "fit":
+		scale = min(scaleX, scaleY)
+	case "fill":
+		scale = max(scaleX, scaleY)
+	case "stretch":
+		return box, nil
+	case "center":
+		scale = 1
+	default:
+		return box, fmt.Errorf("unknown icon scale mode: %s", mode)
+	}
+	width := max(int(float64(size.X)*scale+0.5), 1)
+	height := max(int(float64(size.Y)*scale+0.5), 1)
+	min := box.Min.Add(image.Point{X: (box.Dx() - width) / 2, Y: (box.Dy() - height) / 2})
+	return image.Rectangle{Min: min, Max: min.Add(image.Point{X: width, Y: height})}, nil
+}
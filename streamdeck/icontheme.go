@@ -0,0 +1,353 @@
+package streamdeck
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The icon theme every theme falls back to, as the freedesktop icon theme
+// specification requires.
+const fallbackIconTheme = "hicolor"
+
+// iconExtensions are the icon formats that can be decoded, in order of
+// preference.
+var iconExtensions = []string{".png", ".svg"}
+
+// iconTheme is a parsed index.theme file.
+type iconTheme struct {
+	dirs     []string
+	inherits []string
+	subdirs  []iconThemeDir
+}
+
+// iconThemeDir is one directory of an icon theme with its icon sizes in
+// pixels, the scale already applied.
+type iconThemeDir struct {
+	path      string
+	kind      string
+	size      int
+	minSize   int
+	maxSize   int
+	threshold int
+}
+
+type themeIconKey struct {
+	theme string
+	name  string
+	size  int
+}
+
+// iconThemeCache keeps parsed themes and resolved icon paths, so theme
+// directories are searched once per icon and size.
+type iconThemeCache struct {
+	lock   sync.Mutex
+	themes map[string]*iconTheme
+	paths  map[themeIconKey]string
+}
+
+var iconThemes = &iconThemeCache{
+	themes: make(map[string]*iconTheme),
+	paths:  make(map[themeIconKey]string),
+}
+
+func (c *iconThemeCache) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.themes = make(map[string]*iconTheme)
+	c.paths = make(map[themeIconKey]string)
+}
+
+// SetIconTheme sets the icon theme used to find theme icons. The default
+// is the GTK icon theme of the user, or Adwaita.
+func (dd *DeckDevice) SetIconTheme(name string) {
+	dd.iconTheme = name
+}
+
+// find returns the file of the named icon closest to size pixels,
+// or an empty string. The theme directories are searched without the lock,
+// which is only held for the cache, so a slow search does not hold up the
+// icons already found.
+func (c *iconThemeCache) find(theme string, name string, size int) string {
+	key := themeIconKey{theme: theme, name: name, size: size}
+	c.lock.Lock()
+	path, exists := c.paths[key]
+	c.lock.Unlock()
+	if exists {
+		return path
+	}
+
+	visited := make(map[string]bool)
+	path = c.findInTheme(theme, name, size, visited)
+	if path == "" {
+		path = c.findInTheme(fallbackIconTheme, name, size, visited)
+	}
+	if path == "" {
+		path = findUnthemedIcon(name)
+	}
+	c.lock.Lock()
+	c.paths[key] = path
+	c.lock.Unlock()
+	return path
+}
+
+// findInTheme looks the icon up in the theme and then in the themes it
+// inherits from.
+func (c *iconThemeCache) findInTheme(name string, icon string, size int, visited map[string]bool) string {
+	if visited[name] {
+		return ""
+	}
+	visited[name] = true
+	theme := c.load(name)
+	if theme == nil {
+		return ""
+	}
+	if path := theme.lookup(icon, size); path != "" {
+		return path
+	}
+	for _, parent := range theme.inherits {
+		if path := c.findInTheme(parent, icon, size, visited); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// load returns the named theme, or nil when it is not installed. A theme is
+// parsed without the lock, and the first one parsed is kept when two
+// searches parse it at once.
+func (c *iconThemeCache) load(name string) *iconTheme {
+	c.lock.Lock()
+	theme, exists := c.themes[name]
+	c.lock.Unlock()
+	if exists {
+		return theme
+	}
+	for _, base := range iconBaseDirs() {
+		dir := filepath.Join(base, name)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		if theme == nil {
+			parsed, err := parseIconTheme(filepath.Join(dir, "index.theme"))
+			if err != nil {
+				continue
+			}
+			theme = parsed
+		}
+		theme.dirs = append(theme.dirs, dir)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if loaded, exists := c.themes[name]; exists {
+		return loaded
+	}
+	c.themes[name] = theme
+	return theme
+}
+
+// lookup returns the icon of an exactly matching directory, or else of the
+// directory closest in size.
+func (t *iconTheme) lookup(name string, size int) string {
+	for _, subdir := range t.subdirs {
+		if !subdir.matches(size) {
+			continue
+		}
+		if path := t.iconFile(subdir, name); path != "" {
+			return path
+		}
+	}
+	closest, distance := "", 0
+	for _, subdir := range t.subdirs {
+		d := subdir.distance(size)
+		if closest != "" && d >= distance {
+			continue
+		}
+		if path := t.iconFile(subdir, name); path != "" {
+			closest, distance = path, d
+		}
+	}
+	return closest
+}
+
+func (t *iconTheme) iconFile(subdir iconThemeDir, name string) string {
+	for _, dir := range t.dirs {
+		for _, ext := range iconExtensions {
+			path := filepath.Join(dir, subdir.path, name+ext)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+func (d iconThemeDir) matches(size int) bool {
+	switch d.kind {
+	case "Fixed":
+		return size == d.size
+	case "Scalable":
+		return size >= d.minSize && size <= d.maxSize
+	default:
+		return size >= d.size-d.threshold && size <= d.size+d.threshold
+	}
+}
+
+func (d iconThemeDir) distance(size int) int {
+	low, high := d.size-d.threshold, d.size+d.threshold
+	switch d.kind {
+	case "Fixed":
+		low, high = d.size, d.size
+	case "Scalable":
+		low, high = d.minSize, d.maxSize
+	}
+	if size < low {
+		return low - size
+	}
+	if size > high {
+		return size - high
+	}
+	return 0
+}
+
+// parseIconTheme reads the directories and parent themes of an index.theme
+// file.
+func parseIconTheme(path string) (*iconTheme, error) {
+	sections, err := parseDesktopFile(path)
+	if err != nil {
+		return nil, err
+	}
+	main := sections["Icon Theme"]
+	theme := &iconTheme{inherits: splitList(main["Inherits"])}
+	for _, name := range append(splitList(main["Directories"]), splitList(main["ScaledDirectories"])...) {
+		section, exists := sections[name]
+		if !exists {
+			continue
+		}
+		size := atoiOr(section["Size"], 0)
+		scale := max(atoiOr(section["Scale"], 1), 1)
+		kind := section["Type"]
+		if kind == "" {
+			kind = "Threshold"
+		}
+		theme.subdirs = append(theme.subdirs, iconThemeDir{
+			path:      name,
+			kind:      kind,
+			size:      size * scale,
+			minSize:   atoiOr(section["MinSize"], size) * scale,
+			maxSize:   atoiOr(section["MaxSize"], size) * scale,
+			threshold: atoiOr(section["Threshold"], 2) * scale,
+		})
+	}
+	return theme, nil
+}
+
+// parseDesktopFile reads the keys of each group of a desktop entry style
+// file like index.theme or settings.ini.
+func parseDesktopFile(path string) (map[string]map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sections := make(map[string]map[string]string)
+	var section map[string]string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = make(map[string]string)
+			sections[line[1:len(line)-1]] = section
+		case section != nil:
+			key, value, found := strings.Cut(line, "=")
+			if found {
+				section[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+	}
+	return sections, scanner.Err()
+}
+
+// findUnthemedIcon looks for the icon directly in the base directories, as
+// the specification allows for icons outside any theme.
+func findUnthemedIcon(name string) string {
+	for _, base := range iconBaseDirs() {
+		for _, ext := range iconExtensions {
+			path := filepath.Join(base, name+ext)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// iconBaseDirs returns the directories searched for icon themes, in order.
+func iconBaseDirs() []string {
+	var dirs []string
+	home, _ := os.UserHomeDir()
+	if home != "" {
+		dirs = append(dirs, filepath.Join(home, ".icons"))
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" && home != "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	if dataHome != "" {
+		dirs = append(dirs, filepath.Join(dataHome, "icons"))
+	}
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range filepath.SplitList(dataDirs) {
+		if dir != "" {
+			dirs = append(dirs, filepath.Join(dir, "icons"))
+		}
+	}
+	return append(dirs, "/usr/share/pixmaps")
+}
+
+// defaultIconTheme returns the icon theme set in the GTK settings of the
+// user, or Adwaita.
+func defaultIconTheme() string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, _ := os.UserHomeDir()
+		configHome = filepath.Join(home, ".config")
+	}
+	for _, version := range []string{"gtk-4.0", "gtk-3.0"} {
+		sections, err := parseDesktopFile(filepath.Join(configHome, version, "settings.ini"))
+		if err != nil {
+			continue
+		}
+		if name := sections["Settings"]["gtk-icon-theme-name"]; name != "" {
+			return name
+		}
+	}
+	return "Adwaita"
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func atoiOr(value string, fallback int) int {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+	return i
+}
@@ -38,6 +38,80 @@ func (dd *DeckDevice) SetImage(keyIndex uint8, img image.Image) error {
 	if err != nil {
 		return err
 	}
+	return dd.SetEncodedImage(keyIndex, img, imageData)
+}
+
+// EncodeImage converts the image to the format of the device, so it can be
+// shown many times with SetEncodedImage without converting it again.
+func (dd *DeckDevice) EncodeImage(img image.Image) (*ImageData, error) {
+	return dd.prepareImage(img)
+}
+
+// SetEncodedImage shows an image encoded with EncodeImage. Like with SetImage
+// img becomes the image returned by KeyImage.
+func (dd *DeckDevice) SetEncodedImage(keyIndex uint8, img image.Image, imageData *ImageData) error {
+	if dd.writeLock != nil {
+		dd.writeLock.Lock()
+		defer dd.writeLock.Unlock()
+	}
+	if dd.keyImages != nil {
+		dd.keyImages[keyIndex] = img
+	}
+	if dd.held {
+		return nil
+	}
+	return dd.writeImage(keyIndex, imageData)
+}
+
+// SetTemporaryImage shows the image on the key without replacing the image
+// returned by KeyImage, so RestoreImage can bring the previous one back.
+func (dd *DeckDevice) SetTemporaryImage(keyIndex uint8, img image.Image) error {
+	imageData, err := dd.prepareImage(img)
+	if err != nil {
+		return err
+	}
+	if dd.writeLock != nil {
+		dd.writeLock.Lock()
+		defer dd.writeLock.Unlock()
+	}
+	if dd.held {
+		return nil
+	}
+	return dd.writeImage(keyIndex, imageData)
+}
+
+// KeepImage makes img the image returned by KeyImage and shown again by
+// RestoreImage, without showing it now.
+func (dd *DeckDevice) KeepImage(keyIndex uint8, img image.Image) error {
+	if dd.writeLock != nil {
+		dd.writeLock.Lock()
+		defer dd.writeLock.Unlock()
+	}
+	if dd.keyImages != nil {
+		dd.keyImages[keyIndex] = img
+	}
+	return nil
+}
+
+// RestoreImage shows the last image set with SetImage again.
+func (dd *DeckDevice) RestoreImage(keyIndex uint8) error {
+	img := dd.KeyImage(keyIndex)
+	if img == nil {
+		return nil
+	}
+	return dd.SetImage(keyIndex, img)
+}
+
+// KeyImage returns the last image set on the key with SetImage, or nil.
+func (dd *DeckDevice) KeyImage(keyIndex uint8) image.Image {
+	if dd.writeLock != nil {
+		dd.writeLock.Lock()
+		defer dd.writeLock.Unlock()
+	}
+	return dd.keyImages[keyIndex]
+}
+
+func (dd *DeckDevice) writeImage(keyIndex uint8, imageData *ImageData) error {
 	data := make([]byte, dd.imagePageSize)
 	translatedIndex := dd.translateKeyIndex(keyIndex, dd.Columns)
 
@@ -91,7 +165,8 @@ func resizeImage(src image.Image, width, height int) image.Image {
 }
 
 func (dd *DeckDevice) prepareImage(img image.Image) (*ImageData, error) {
-	// Auto-resize if dimensions don't match
+	// Auto-resize if dimensions don't match. Icons are scaled by their
+	// scale mode before, so only other images are stretched here.
 	if img.Bounds().Dy() != int(dd.Pixels) || img.Bounds().Dx() != int(dd.Pixels) {
 		img = resizeImage(img, int(dd.Pixels), int(dd.Pixels))
 	}
@@ -130,6 +205,11 @@ func (dd *DeckDevice) Clear() error {
 	return nil
 }
 
+// ClearKey sets a black image on the key.
+func (dd *DeckDevice) ClearKey(index uint8) error {
+	return dd.SetImage(index, createNewRGBAImage(int(dd.Pixels), int(dd.Pixels), black))
+}
+
 type ImageData struct {
 	image     []byte
 	pageSize  int
@@ -0,0 +1,47 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+
+	"angrysoft.ovh/angry-deck/page"
+	"golang.org/x/image/draw"
+)
+
+// drawLayers draws the layers of an icon over dst inside area, in order.
+func (dd *DeckDevice) drawLayers(dst *image.RGBA, dir string, layers []page.Layer, area image.Rectangle) error {
+	for i, layer := range layers {
+		err := dd.drawLayer(dst, dir, layer, area)
+		if err != nil {
+			return fmt.Errorf("layer %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// drawLayer draws what the layer sets, the fill first and the text last.
+// Icons of layers have their own fill, padding, filters and layers.
+func (dd *DeckDevice) drawLayer(dst *image.RGBA, dir string, layer page.Layer, area image.Rectangle) error {
+	err := fillArea(dst, area, layer.Fill)
+	if err != nil {
+		return err
+	}
+	if layer.Icon != nil {
+		err := fillArea(dst, area, layer.Icon.Fill)
+		if err != nil {
+			return err
+		}
+		err = dd.drawIcon(dst, dir, *layer.Icon, area)
+		if err != nil {
+			return err
+		}
+	}
+	if layer.Text != nil && layer.Text.Text != "" {
+		img, err := dd.DrawLabel(dst.SubImage(area), *layer.Text)
+		if err != nil {
+			return fmt.Errorf("cannot set text on image: %w", err)
+		}
+		draw.Draw(dst, area, img, area.Min, draw.Src)
+	}
+	return nil
+}
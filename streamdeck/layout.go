@@ -0,0 +1,366 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"angrysoft.ovh/angry-deck/page"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const minAutoFitFontSize = 6
+
+// textLayout is a label broken into lines and positioned inside a box.
+type textLayout struct {
+	face   font.Face
+	lines  []string
+	widths []fixed.Int26_6
+	height fixed.Int26_6
+	broken bool
+}
+
+// DrawLabel draws the label text inside the key, honouring its alignment,
+// explicit line breaks, word wrapping and auto fit, on a pill of the
+// background colour if it has one.
+func (dd *DeckDevice) DrawLabel(img image.Image, label page.Label) (image.Image, error) {
+	return dd.drawLabel(img, label, 0)
+}
+
+// DrawMarquee draws a marquee label scrolled left by offset pixels. The text
+// repeats after a gap, so increasing offsets scroll it in a loop.
+func (dd *DeckDevice) DrawMarquee(img image.Image, label page.Label, offset int) (image.Image, error) {
+	return dd.drawLabel(img, label, offset)
+}
+
+// MarqueeOverflows reports whether the label is a marquee too wide for the
+// key, which is scrolled.
+func (dd *DeckDevice) MarqueeOverflows(label page.Label) bool {
+	if !label.Marquee || label.Text == "" {
+		return false
+	}
+	box := image.Rect(0, 0, int(dd.Pixels), int(dd.Pixels)).Inset(dd.textMargin())
+	layout, err := dd.layoutText(dd.fontChain(label.Font, label.FallbackFonts), label, box)
+	return err == nil && layout.maxWidth() > fixed.I(box.Dx())
+}
+
+func (dd *DeckDevice) drawLabel(img image.Image, label page.Label, offset int) (image.Image, error) {
+	result := image.NewRGBA(img.Bounds())
+	draw.Draw(result, result.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	chain := dd.fontChain(label.Font, label.FallbackFonts)
+	vertical, horizontal, err := parseAlign(label.Align)
+	if err != nil {
+		return result, err
+	}
+
+	box := result.Bounds().Inset(dd.textMargin())
+	layout, err := dd.layoutText(chain, label, box)
+	if err != nil {
+		return result, err
+	}
+
+	metrics := layout.face.Metrics()
+	y := fixed.I(box.Min.Y)
+	switch vertical {
+	case "center":
+		y += (fixed.I(box.Dy()) - layout.height) / 2
+	case "bottom":
+		y = fixed.I(box.Max.Y) - layout.height
+	}
+	// Overflowing marquee text starts at the left edge and scrolls, followed
+	// by a copy one period later.
+	scrolling := label.Marquee && layout.maxWidth() > fixed.I(box.Dx())
+	period := layout.maxWidth().Ceil() + box.Dx()/3
+	copies := []int{0}
+	if scrolling {
+		copies = append(copies, period)
+	}
+	dots := make([]fixed.Point26_6, len(layout.lines))
+	for i := range layout.lines {
+		x := fixed.I(box.Min.X)
+		switch {
+		case scrolling:
+			x -= fixed.I(offset % period)
+		case horizontal == "center":
+			x += (fixed.I(box.Dx()) - layout.widths[i]) / 2
+		case horizontal == "right":
+			x = fixed.I(box.Max.X) - layout.widths[i]
+		}
+		dots[i] = fixed.Point26_6{X: x, Y: y + metrics.Ascent}
+		y += metrics.Height
+	}
+
+	textArea := layout.bounds(dots)
+	if scrolling {
+		textArea.Min.X, textArea.Max.X = box.Min.X, box.Max.X
+	}
+	if label.Background != "" {
+		background, err := page.ParseColor(label.Background)
+		if err != nil {
+			return result, err
+		}
+		pad := max(int(dd.Pixels)/36, 1)
+		pill := image.Rect(textArea.Min.X-2*pad, textArea.Min.Y-pad, textArea.Max.X+2*pad, textArea.Max.Y+pad)
+		fillRoundedRect(result, pill, pill.Dy()/2, background)
+	}
+
+	col, err := textColor(label.FontColor, result, textArea)
+	if err != nil {
+		return result, err
+	}
+	// Scrolling text is clipped at the sides of the text box.
+	target := result
+	if scrolling {
+		target = result.SubImage(image.Rect(box.Min.X, result.Bounds().Min.Y, box.Max.X, result.Bounds().Max.Y)).(*image.RGBA)
+	}
+	// Colour glyphs are drawn as silhouettes in the shadow and outline.
+	drawLines := func(c color.Color, offset image.Point, colorGlyphs bool) {
+		for _, copyX := range copies {
+			for i, line := range layout.lines {
+				dot := dots[i].Add(fixed.P(offset.X+copyX, offset.Y))
+				drawString(target, layout.face, image.NewUniform(c), dot, line, colorGlyphs)
+			}
+		}
+	}
+
+	if label.Shadow {
+		offset := max(int(dd.Pixels)/48, 1)
+		drawLines(shadowColor, image.Point{X: offset, Y: offset}, false)
+	}
+	if label.OutlineWidth > 0 {
+		outline := contrastColor(col)
+		if label.OutlineColor != "" {
+			outline, err = page.ParseColor(label.OutlineColor)
+			if err != nil {
+				return result, err
+			}
+		}
+		w := label.OutlineWidth
+		for dy := -w; dy <= w; dy++ {
+			for dx := -w; dx <= w; dx++ {
+				if (dx != 0 || dy != 0) && dx*dx+dy*dy <= w*w {
+					drawLines(outline, image.Point{X: dx, Y: dy}, false)
+				}
+			}
+		}
+	}
+	drawLines(col, image.Point{}, true)
+	return result, nil
+}
+
+// bounds returns the rectangle covered by the lines drawn at dots.
+func (l *textLayout) bounds(dots []fixed.Point26_6) image.Rectangle {
+	var r image.Rectangle
+	metrics := l.face.Metrics()
+	for i, dot := range dots {
+		line := image.Rect(
+			dot.X.Floor(), (dot.Y - metrics.Ascent).Floor(),
+			(dot.X + l.widths[i]).Ceil(), (dot.Y + metrics.Descent).Ceil(),
+		)
+		r = r.Union(line)
+	}
+	return r
+}
+
+// textMargin is the distance between the key edge and the text box, the
+// padding of the device.
+func (dd *DeckDevice) textMargin() int {
+	return int(dd.Padding)
+}
+
+// layoutText breaks the label into lines. With AutoFit the font size is
+// decreased until every line fits the box without breaking words. Marquee
+// labels are neither wrapped nor shrunk, they scroll instead.
+func (dd *DeckDevice) layoutText(chain []*opentype.Font, label page.Label, box image.Rectangle) (*textLayout, error) {
+	size := label.FontSize
+	if size == 0 {
+		size = defaultFontSize
+	}
+	text := strings.ReplaceAll(label.Text, `\n`, "\n")
+
+	for {
+		face, err := dd.newFace(chain, size)
+		if err != nil {
+			return nil, err
+		}
+		layout := measureLines(face, text, label.Wrap && !label.Marquee, fixed.I(box.Dx()))
+		if !label.AutoFit || label.Marquee || size <= minAutoFitFontSize || layout.fits(box) {
+			return layout, nil
+		}
+		size--
+	}
+}
+
+func (l *textLayout) maxWidth() fixed.Int26_6 {
+	var width fixed.Int26_6
+	for _, w := range l.widths {
+		width = max(width, w)
+	}
+	return width
+}
+
+func (l *textLayout) fits(box image.Rectangle) bool {
+	if l.broken || l.height > fixed.I(box.Dy()) {
+		return false
+	}
+	for _, width := range l.widths {
+		if width > fixed.I(box.Dx()) {
+			return false
+		}
+	}
+	return true
+}
+
+func measureLines(face font.Face, text string, wrap bool, maxWidth fixed.Int26_6) *textLayout {
+	layout := &textLayout{face: face}
+	for _, paragraph := range strings.Split(text, "\n") {
+		if wrap {
+			lines, broken := wrapLine(face, paragraph, maxWidth)
+			layout.lines = append(layout.lines, lines...)
+			layout.broken = layout.broken || broken
+		} else {
+			layout.lines = append(layout.lines, paragraph)
+		}
+	}
+	metrics := face.Metrics()
+	for _, line := range layout.lines {
+		layout.widths = append(layout.widths, font.MeasureString(face, line))
+	}
+	layout.height = metrics.Height*fixed.Int26_6(len(layout.lines)-1) + metrics.Ascent + metrics.Descent
+	return layout
+}
+
+// wrapLine breaks the text at spaces so each line fits maxWidth. Words that
+// are wider than maxWidth on their own are broken between characters, which
+// is reported by broken.
+func wrapLine(face font.Face, text string, maxWidth fixed.Int26_6) (lines []string, broken bool) {
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if font.MeasureString(face, candidate) <= maxWidth {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		line = ""
+		for font.MeasureString(face, word) > maxWidth {
+			head, tail := splitWord(face, word, maxWidth)
+			lines = append(lines, head)
+			word = tail
+			broken = true
+		}
+		line = word
+	}
+	return append(lines, line), broken
+}
+
+// splitWord returns the longest prefix of word that fits maxWidth, keeping
+// at least one character, and the rest.
+func splitWord(face font.Face, word string, maxWidth fixed.Int26_6) (string, string) {
+	runes := []rune(word)
+	n := 1
+	for n < len(runes) && font.MeasureString(face, string(runes[:n+1])) <= maxWidth {
+		n++
+	}
+	return string(runes[:n]), string(runes[n:])
+}
+
+// parseAlign reads alignments like "top left", "center", "bottom" or
+// "center right": the vertical alignment first, then the horizontal one.
+// The default is top left.
+func parseAlign(align string) (vertical, horizontal string, err error) {
+	vertical, horizontal = "top", "left"
+	fields := strings.Fields(strings.ToLower(align))
+	switch len(fields) {
+	case 0:
+	case 1:
+		switch fields[0] {
+		case "top", "bottom":
+			vertical = fields[0]
+		case "left", "right":
+			horizontal = fields[0]
+		case "center", "middle":
+			vertical, horizontal = "center", "center"
+		default:
+			return "", "", fmt.Errorf("unknown text alignment: %s", align)
+		}
+	case 2:
+		switch fields[0] {
+		case "top", "bottom", "center":
+			vertical = fields[0]
+		case "middle":
+			vertical = "center"
+		default:
+			return "", "", fmt.Errorf("unknown vertical text alignment: %s", align)
+		}
+		switch fields[1] {
+		case "left", "right", "center":
+			horizontal = fields[1]
+		default:
+			return "", "", fmt.Errorf("unknown horizontal text alignment: %s", align)
+		}
+	default:
+		return "", "", fmt.Errorf("unknown text alignment: %s", align)
+	}
+	return vertical, horizontal, nil
+}
+
+var (
+	white       = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	black       = color.RGBA{A: 255}
+	shadowColor = color.RGBA{A: 160}
+)
+
+// textColor parses the font colour. "auto" picks black or white, whichever
+// contrasts more with the background under the text. The default is white.
+func textColor(fontColor string, background image.Image, area image.Rectangle) (color.RGBA, error) {
+	switch fontColor {
+	case "":
+		return white, nil
+	case "auto":
+		if luminance(background, area) > 0.5 {
+			return black, nil
+		}
+		return white, nil
+	default:
+		return page.ParseColor(fontColor)
+	}
+}
+
+// contrastColor returns black for light colours and white for dark ones.
+func contrastColor(c color.RGBA) color.RGBA {
+	if relativeLuminance(c) > 0.5 {
+		return black
+	}
+	return white
+}
+
+// luminance returns the average relative luminance, from 0 to 1, of the
+// image inside area.
+func luminance(img image.Image, area image.Rectangle) float64 {
+	area = area.Intersect(img.Bounds())
+	if area.Empty() {
+		return 0
+	}
+	sum := 0.0
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			sum += relativeLuminance(color.RGBAModel.Convert(img.At(x, y)).(color.RGBA))
+		}
+	}
+	return sum / float64(area.Dx()*area.Dy())
+}
+
+func relativeLuminance(c color.RGBA) float64 {
+	return (0.2126*float64(c.R) + 0.7152*float64(c.G) + 0.0722*float64(c.B)) / 255
+}
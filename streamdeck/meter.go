@@ -0,0 +1,101 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	imgDraw "image/draw"
+	"math"
+
+	"angrysoft.ovh/angry-deck/page"
+)
+
+const defaultMeterColor = "#4CAF50"
+
+var meterTrackColor = color.RGBA{R: 64, G: 64, B: 64, A: 255}
+
+// Meter is a value drawn on top of a button as a bar or a gauge, with its
+// label in the middle.
+type Meter struct {
+	Label   page.Label
+	Ratio   float64
+	Display string
+	Color   string
+}
+
+// DrawMeter draws the meter label with a bar along the bottom edge or a
+// gauge around the text.
+func (dd *DeckDevice) DrawMeter(base image.Image, meter Meter) (image.Image, error) {
+	size := int(dd.Pixels)
+	if base.Bounds().Dx() != size || base.Bounds().Dy() != size {
+		base = resizeImage(base, size, size)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	imgDraw.Draw(img, img.Bounds(), base, base.Bounds().Min, imgDraw.Src)
+
+	colorHex := meter.Color
+	if colorHex == "" {
+		colorHex = defaultMeterColor
+	}
+	col, err := page.ParseColor(colorHex)
+	if err != nil {
+		return nil, err
+	}
+
+	switch meter.Display {
+	case "gauge":
+		drawGauge(img, meter.Ratio, col)
+	default:
+		drawBar(img, meter.Ratio, col)
+	}
+
+	if meter.Label.Text == "" {
+		return img, nil
+	}
+	return dd.DrawLabel(img, meter.Label)
+}
+
+func drawBar(img *image.RGBA, ratio float64, col color.Color) {
+	size := img.Bounds().Dx()
+	height := size / 8
+	margin := size / 12
+	track := image.Rect(margin, size-margin-height, size-margin, size-margin)
+	imgDraw.Draw(img, track, image.NewUniform(meterTrackColor), image.Point{}, imgDraw.Src)
+
+	filled := track
+	filled.Max.X = track.Min.X + int(math.Round(float64(track.Dx())*ratio))
+	imgDraw.Draw(img, filled, image.NewUniform(col), image.Point{}, imgDraw.Src)
+}
+
+// drawGauge draws a 270 degree arc open at the bottom, filled clockwise.
+func drawGauge(img *image.RGBA, ratio float64, col color.Color) {
+	const sweep = 1.5 * math.Pi
+	size := float64(img.Bounds().Dx())
+	center := size / 2
+	outer := size * 0.45
+	inner := outer - size/10
+
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			dx := float64(x) + 0.5 - center
+			dy := float64(y) + 0.5 - center
+			dist := math.Hypot(dx, dy)
+			if dist < inner || dist > outer {
+				continue
+			}
+			// Angle measured clockwise from the start of the arc at the
+			// bottom left.
+			angle := math.Atan2(dx, -dy) + 0.75*math.Pi
+			if angle < 0 {
+				angle += 2 * math.Pi
+			}
+			if angle > sweep {
+				continue
+			}
+			if angle <= sweep*ratio {
+				img.Set(x, y, col)
+			} else {
+				img.Set(x, y, meterTrackColor)
+			}
+		}
+	}
+}
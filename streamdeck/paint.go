@@ -0,0 +1,96 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"angrysoft.ovh/angry-deck/page"
+	"golang.org/x/image/draw"
+)
+
+// fillArea blends a fill over area. An empty fill leaves it as it is.
+func fillArea(dst *image.RGBA, area image.Rectangle, fill string) error {
+	if fill == "" {
+		return nil
+	}
+	paint, err := page.ParsePaint(fill)
+	if err != nil {
+		return fmt.Errorf("cannot parse fill color: %w", err)
+	}
+	fillPaint(dst, area, paint)
+	return nil
+}
+
+// fillPaint blends a solid colour or a gradient spanning area over it.
+func fillPaint(dst *image.RGBA, area image.Rectangle, paint page.Paint) {
+	if paint.Type == "solid" {
+		fillRoundedRect(dst, area, 0, paint.Color)
+		return
+	}
+	offset := gradientOffset(paint, area)
+	img := image.NewRGBA(area)
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			img.SetRGBA(x, y, stopColor(paint.Stops, offset(float64(x)+0.5, float64(y)+0.5)))
+		}
+	}
+	draw.Draw(dst, area, img, area.Min, draw.Over)
+}
+
+// gradientOffset returns the function giving the position along the
+// gradient, from 0 to 1, of a point in area. Linear gradients run from the
+// corner where they start to the opposite one, radial ones from the centre
+// to the farthest corner.
+func gradientOffset(paint page.Paint, area image.Rectangle) func(x, y float64) float64 {
+	cx := float64(area.Min.X+area.Max.X) / 2
+	cy := float64(area.Min.Y+area.Max.Y) / 2
+	if paint.Type == "radial" {
+		radius := math.Hypot(float64(area.Dx()), float64(area.Dy())) / 2
+		return func(x, y float64) float64 {
+			if radius == 0 {
+				return 0
+			}
+			return math.Hypot(x-cx, y-cy) / radius
+		}
+	}
+	// 0 degrees points up, and y grows downwards.
+	angle := paint.Angle * math.Pi / 180
+	dx, dy := math.Sin(angle), -math.Cos(angle)
+	length := math.Abs(dx)*float64(area.Dx()) + math.Abs(dy)*float64(area.Dy())
+	return func(x, y float64) float64 {
+		if length == 0 {
+			return 0.5
+		}
+		return ((x-cx)*dx+(y-cy)*dy)/length + 0.5
+	}
+}
+
+// stopColor returns the premultiplied colour of the gradient at offset t.
+func stopColor(stops []page.ColorStop, t float64) color.RGBA {
+	if t <= stops[0].Offset {
+		return mixColors(stops[0].Color, stops[0].Color, 0)
+	}
+	for i := 1; i < len(stops); i++ {
+		if t <= stops[i].Offset {
+			from, to := stops[i-1], stops[i]
+			if to.Offset == from.Offset {
+				return mixColors(to.Color, to.Color, 0)
+			}
+			return mixColors(from.Color, to.Color, (t-from.Offset)/(to.Offset-from.Offset))
+		}
+	}
+	last := stops[len(stops)-1].Color
+	return mixColors(last, last, 0)
+}
+
+// mixColors interpolates between two colours with straight alpha and
+// returns the premultiplied result.
+func mixColors(from, to color.RGBA, t float64) color.RGBA {
+	a := (float64(from.A) + (float64(to.A)-float64(from.A))*t) / 255
+	mix := func(c0, c1 uint8) uint8 {
+		return premultiplied((float64(c0)+(float64(c1)-float64(c0))*t)/255, a)
+	}
+	return color.RGBA{R: mix(from.R, to.R), G: mix(from.G, to.G), B: mix(from.B, to.B), A: uint8(a*255 + 0.5)}
+}
@@ -0,0 +1,110 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+func isSVG(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".svg")
+}
+
+// loadSVG parses an SVG icon. Icons drawn in currentColor, like most
+// symbolic icon sets, are drawn white so they can be tinted.
+func loadSVG(path string) (*oksvg.SvgIcon, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open image file: %v", err)
+	}
+	defer file.Close()
+
+	icon, err := oksvg.ReadReplacingCurrentColor(file, "#FFFFFF", oksvg.IgnoreErrorMode)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode svg file: %v", err)
+	}
+	return icon, nil
+}
+
+// svgSize returns the size of the view box of the icon, at least one pixel.
+func svgSize(icon *oksvg.SvgIcon) image.Point {
+	return image.Point{
+		X: max(int(icon.ViewBox.W+0.5), 1),
+		Y: max(int(icon.ViewBox.H+0.5), 1),
+	}
+}
+
+// renderSVG rasterises the icon into the target rectangle, so it is drawn
+// at the resolution of the key instead of being scaled as a bitmap.
+func renderSVG(icon *oksvg.SvgIcon, target image.Rectangle) *image.RGBA {
+	// The scanner draws from the corner of the image bounds, so the icon is
+	// rendered at the origin and the image moved to the target afterwards.
+	img := image.NewRGBA(image.Rect(0, 0, target.Dx(), target.Dy()))
+	icon.SetTarget(0, 0, float64(target.Dx()), float64(target.Dy()))
+	scanner := rasterx.NewScannerGV(target.Dx(), target.Dy(), img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(target.Dx(), target.Dy(), scanner), 1)
+	img.Rect = target
+	return img
+}
+
+// monochrome reports whether the visible pixels of the image are all of one
+// colour, telling apart symbolic icons from full colour ones. Edges drawn
+// nearly transparent are left out, as their colour is not reliable.
+func monochrome(img *image.RGBA) bool {
+	const tolerance = 16
+	var first color.RGBA
+	found := false
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			if c.A < 32 {
+				continue
+			}
+			// Unpremultiply, so the edges compare with the middle.
+			c = color.RGBA{
+				R: uint8(uint32(c.R) * 255 / uint32(c.A)),
+				G: uint8(uint32(c.G) * 255 / uint32(c.A)),
+				B: uint8(uint32(c.B) * 255 / uint32(c.A)),
+			}
+			if !found {
+				first, found = c, true
+				continue
+			}
+			if absDiff(c.R, first.R) > tolerance || absDiff(c.G, first.G) > tolerance || absDiff(c.B, first.B) > tolerance {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// tintImage recolours every pixel with the tint, keeping its alpha, which
+// suits monochrome icons.
+func tintImage(img *image.RGBA, tint color.RGBA) {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			a := uint32(img.RGBAAt(x, y).A) * uint32(tint.A) / 255
+			img.SetRGBA(x, y, color.RGBA{
+				R: uint8(uint32(tint.R) * a / 255),
+				G: uint8(uint32(tint.G) * a / 255),
+				B: uint8(uint32(tint.B) * a / 255),
+				A: uint8(a),
+			})
+		}
+	}
+}
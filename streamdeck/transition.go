@@ -0,0 +1,186 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	imgDraw "image/draw"
+	"maps"
+	"time"
+)
+
+// Transition frames are sent at most this often. Every frame rewrites all
+// keys, so faster rates only queue up writes.
+const transitionFrameInterval = 40 * time.Millisecond
+
+// SupportsTransitions reports whether the device is fast enough to stream
+// transition frames. Rev1 devices take BMP images over slow writes.
+func (dd *DeckDevice) SupportsTransitions() bool {
+	switch dd.revision {
+	case rev1:
+		return false
+	default:
+		return true
+	}
+}
+
+// Hold keeps the images set on keys from the device, so a page can be drawn
+// off-screen. KeyImage returns them as usual. Transition releases the hold.
+func (dd *DeckDevice) Hold() {
+	if dd.writeLock != nil {
+		dd.writeLock.Lock()
+		defer dd.writeLock.Unlock()
+	}
+	dd.held = true
+}
+
+// KeyImages returns a copy of the last image set on every key.
+func (dd *DeckDevice) KeyImages() map[uint8]image.Image {
+	if dd.writeLock != nil {
+		dd.writeLock.Lock()
+		defer dd.writeLock.Unlock()
+	}
+	return maps.Clone(dd.keyImages)
+}
+
+// Transition streams frames going from the key images in from to the ones
+// set while the device was held, then releases the hold and shows the final
+// images. Styles are slide_left, slide_right, fade and wipe.
+func (dd *DeckDevice) Transition(from map[uint8]image.Image, style string, duration time.Duration) error {
+	defer dd.release()
+	frame, err := transitionFunc(style)
+	if err != nil {
+		return err
+	}
+	oldGrid := dd.composeGrid(from)
+	newGrid := dd.composeGrid(dd.KeyImages())
+
+	frames := int(duration / transitionFrameInterval)
+	ticker := time.NewTicker(transitionFrameInterval)
+	defer ticker.Stop()
+	for i := 1; i < frames; i++ {
+		progress := float64(i) / float64(frames)
+		err := dd.writeGrid(frame(dd, oldGrid, newGrid, progress))
+		if err != nil {
+			return err
+		}
+		<-ticker.C
+	}
+	return nil
+}
+
+// release ends the hold and writes the images of all keys.
+func (dd *DeckDevice) release() {
+	if dd.writeLock != nil {
+		dd.writeLock.Lock()
+	}
+	dd.held = false
+	images := maps.Clone(dd.keyImages)
+	if dd.writeLock != nil {
+		dd.writeLock.Unlock()
+	}
+	for key := uint8(0); key < dd.Keys; key++ {
+		if img, exists := images[key]; exists {
+			err := dd.SetImage(key, img)
+			if err != nil {
+				fmt.Println("Error setting key image:", err)
+			}
+		}
+	}
+}
+
+type transitionFrame func(dd *DeckDevice, from, to *image.RGBA, progress float64) *image.RGBA
+
+func transitionFunc(style string) (transitionFrame, error) {
+	switch style {
+	case "slide_left":
+		return slideFrame(-1), nil
+	case "slide_right":
+		return slideFrame(1), nil
+	case "fade":
+		return fadeFrame, nil
+	case "wipe":
+		return wipeFrame, nil
+	}
+	return nil, fmt.Errorf("unknown page transition: %s", style)
+}
+
+// slideFrame moves the old page out and the new one in, to the left for a
+// negative direction.
+func slideFrame(direction int) transitionFrame {
+	return func(dd *DeckDevice, from, to *image.RGBA, progress float64) *image.RGBA {
+		width := from.Bounds().Dx()
+		offset := int(float64(width) * progress)
+		frame := image.NewRGBA(from.Bounds())
+		oldAt, newAt := direction*offset, direction*(offset-width)
+		imgDraw.Draw(frame, from.Bounds().Add(image.Point{X: oldAt}), from, image.Point{}, imgDraw.Src)
+		imgDraw.Draw(frame, to.Bounds().Add(image.Point{X: newAt}), to, image.Point{}, imgDraw.Src)
+		return frame
+	}
+}
+
+// fadeFrame fades the old page to black and the new one in from black.
+func fadeFrame(dd *DeckDevice, from, to *image.RGBA, progress float64) *image.RGBA {
+	src, level := from, 1-2*progress
+	if progress >= 0.5 {
+		src, level = to, 2*progress-1
+	}
+	return mapPixels(src, func(c color.RGBA) color.RGBA {
+		return color.RGBA{
+			R: uint8(float64(c.R) * level),
+			G: uint8(float64(c.G) * level),
+			B: uint8(float64(c.B) * level),
+			A: c.A,
+		}
+	})
+}
+
+// wipeFrame replaces the old page with the new one column by column, from
+// left to right.
+func wipeFrame(dd *DeckDevice, from, to *image.RGBA, progress float64) *image.RGBA {
+	frame := image.NewRGBA(from.Bounds())
+	imgDraw.Draw(frame, frame.Bounds(), from, image.Point{}, imgDraw.Src)
+	columns := int(float64(dd.Columns)*progress + 0.5)
+	step := int(dd.Pixels + dd.Gap)
+	revealed := image.Rect(0, 0, columns*step, frame.Bounds().Dy())
+	imgDraw.Draw(frame, revealed, to, image.Point{}, imgDraw.Src)
+	return frame
+}
+
+// composeGrid draws the key images into one image of the key grid.
+func (dd *DeckDevice) composeGrid(images map[uint8]image.Image) *image.RGBA {
+	size := dd.SpanSize(image.Point{X: int(dd.Columns), Y: int(dd.Rows)})
+	grid := createNewRGBAImage(size.X, size.Y, black).(*image.RGBA)
+	for key := uint8(0); key < dd.Keys; key++ {
+		img, exists := images[key]
+		if !exists || img == nil {
+			continue
+		}
+		origin := dd.keyOrigin(key)
+		area := image.Rectangle{Min: origin, Max: origin.Add(image.Point{X: int(dd.Pixels), Y: int(dd.Pixels)})}
+		imgDraw.Draw(grid, area, img, img.Bounds().Min, imgDraw.Src)
+	}
+	return grid
+}
+
+// writeGrid sends every key of a grid image to the device, bypassing the
+// hold and leaving the key images alone.
+func (dd *DeckDevice) writeGrid(grid *image.RGBA) error {
+	for key := uint8(0); key < dd.Keys; key++ {
+		imageData, err := dd.prepareImage(dd.GridArea(grid, key, image.Point{X: 1, Y: 1}))
+		if err != nil {
+			return err
+		}
+		if dd.writeLock != nil {
+			dd.writeLock.Lock()
+		}
+		err = dd.writeImage(key, imageData)
+		if dd.writeLock != nil {
+			dd.writeLock.Unlock()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package widget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"angrysoft.ovh/angry-deck/page"
+)
+
+const (
+	defaultClockFormat = "15:04"
+	defaultDateFormat  = "Jan 02"
+
+	defaultWork      = 25 * time.Minute
+	defaultBreak     = 5 * time.Minute
+	defaultLongBreak = 15 * time.Minute
+	defaultRounds    = 4
+	defaultWorkFill  = "#C0392B"
+	defaultBreakFill = "#27AE60"
+)
+
+var targetLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Clock keeps the state of a time based button. Stopwatch and pomodoro
+// clocks are started and paused with a key press.
+type Clock struct {
+	cfg     page.Clock
+	target  time.Time
+	running bool
+	started time.Time
+	elapsed time.Duration
+	phase   int
+	fired   bool
+	lock    sync.Mutex
+}
+
+func NewClock(cfg page.Clock) (*Clock, error) {
+	c := &Clock{cfg: cfg}
+	switch cfg.Type {
+	case "clock":
+		if c.cfg.Format == "" {
+			c.cfg.Format = defaultClockFormat
+		}
+	case "date":
+		if c.cfg.Format == "" {
+			c.cfg.Format = defaultDateFormat
+		}
+	case "countdown":
+		target, err := parseTarget(cfg.Target)
+		if err != nil {
+			return nil, err
+		}
+		c.target = target
+	case "stopwatch":
+	case "pomodoro":
+		if c.cfg.Work <= 0 {
+			c.cfg.Work = defaultWork
+		}
+		if c.cfg.Break <= 0 {
+			c.cfg.Break = defaultBreak
+		}
+		if c.cfg.LongBreak <= 0 {
+			c.cfg.LongBreak = defaultLongBreak
+		}
+		if c.cfg.Rounds <= 0 {
+			c.cfg.Rounds = defaultRounds
+		}
+		if c.cfg.WorkFill == "" {
+			c.cfg.WorkFill = defaultWorkFill
+		}
+		if c.cfg.BreakFill == "" {
+			c.cfg.BreakFill = defaultBreakFill
+		}
+	default:
+		return nil, fmt.Errorf("unknown clock type: %s", cfg.Type)
+	}
+	return c, nil
+}
+
+// Interactive reports whether key presses control the clock.
+func (c *Clock) Interactive() bool {
+	return c.cfg.Type == "stopwatch" || c.cfg.Type == "pomodoro"
+}
+
+// Toggle starts or pauses the clock.
+func (c *Clock) Toggle(now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.running {
+		c.elapsed += now.Sub(c.started)
+		c.running = false
+		return
+	}
+	c.started = now
+	c.running = true
+}
+
+// Reset stops the clock and brings it back to its initial state.
+func (c *Clock) Reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.running = false
+	c.elapsed = 0
+	c.phase = 0
+}
+
+// Tick reports whether the countdown has just reached its target or a
+// pomodoro phase has just ended. A finished pomodoro phase starts the next
+// one, so work and breaks alternate until the key pauses the clock.
+func (c *Clock) Tick(now time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	switch c.cfg.Type {
+	case "countdown":
+		if !c.fired && !now.Before(c.target) {
+			c.fired = true
+			return true
+		}
+	case "pomodoro":
+		if c.running && c.elapsedAt(now) >= c.phaseDuration() {
+			// The next phase starts when the last one ended, not at the tick.
+			c.started = c.started.Add(c.phaseDuration() - c.elapsed)
+			c.elapsed = 0
+			c.phase++
+			return true
+		}
+	}
+	return false
+}
+
+// Text returns the value to display at the given time.
+func (c *Clock) Text(now time.Time) string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	switch c.cfg.Type {
+	case "countdown":
+		return formatDuration(c.target.Sub(now))
+	case "stopwatch":
+		return formatDuration(c.elapsedAt(now))
+	case "pomodoro":
+		return formatDuration(c.phaseDuration() - c.elapsedAt(now))
+	default:
+		return now.Format(c.cfg.Format)
+	}
+}
+
+// Fill returns the background colour of the current pomodoro phase, or an
+// empty string for the other clock types.
+func (c *Clock) Fill() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.cfg.Type != "pomodoro" {
+		return ""
+	}
+	if c.isBreak() {
+		return c.cfg.BreakFill
+	}
+	return c.cfg.WorkFill
+}
+
+func (c *Clock) elapsedAt(now time.Time) time.Duration {
+	if c.running {
+		return c.elapsed + now.Sub(c.started)
+	}
+	return c.elapsed
+}
+
+func (c *Clock) isBreak() bool {
+	return c.phase%2 == 1
+}
+
+// phaseDuration returns the length of the current pomodoro phase. Every
+// Rounds work sessions the break is a long one.
+func (c *Clock) phaseDuration() time.Duration {
+	if !c.isBreak() {
+		return c.cfg.Work
+	}
+	if (c.phase+1)%(2*c.cfg.Rounds) == 0 {
+		return c.cfg.LongBreak
+	}
+	return c.cfg.Break
+}
+
+func parseTarget(value string) (time.Time, error) {
+	for _, layout := range targetLayouts {
+		t, err := time.ParseInLocation(layout, value, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse countdown target: %q", value)
+}
+
+// formatDuration formats d as MM:SS, H:MM:SS or Nd HH:MM. Negative durations
+// are shown as zero.
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	total := int(d.Round(time.Second).Seconds())
+	days := total / 86400
+	hours := total % 86400 / 3600
+	minutes := total % 3600 / 60
+	seconds := total % 60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %02d:%02d", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	default:
+		return fmt.Sprintf("%02d:%02d", minutes, seconds)
+	}
+}
@@ -0,0 +1,150 @@
+package widget
+
+import (
+	"testing"
+	"time"
+
+	"angrysoft.ovh/angry-deck/page"
+)
+
+var clockStart = time.Date(2026, 3, 14, 9, 26, 53, 0, time.Local)
+
+func newTestClock(t *testing.T, cfg page.Clock) *Clock {
+	t.Helper()
+	c, err := NewClock(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: -time.Minute, want: "00:00"},
+		{d: 0, want: "00:00"},
+		{d: 1500 * time.Millisecond, want: "00:02"},
+		{d: 25 * time.Minute, want: "25:00"},
+		{d: time.Hour + 2*time.Minute + 3*time.Second, want: "1:02:03"},
+		{d: 50*time.Hour + 30*time.Minute, want: "2d 02:30"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestNewClock(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     page.Clock
+		wantErr bool
+	}{
+		{name: "clock", cfg: page.Clock{Type: "clock"}},
+		{name: "countdown with a date", cfg: page.Clock{Type: "countdown", Target: "2026-12-24"}},
+		{name: "countdown with RFC 3339", cfg: page.Clock{Type: "countdown", Target: "2026-12-24T18:00:00+01:00"}},
+		{name: "countdown without a target", cfg: page.Clock{Type: "countdown"}, wantErr: true},
+		{name: "countdown with a bad target", cfg: page.Clock{Type: "countdown", Target: "tomorrow"}, wantErr: true},
+		{name: "unknown type", cfg: page.Clock{Type: "alarm"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClock(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewClock() error = %v, want an error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClockText(t *testing.T) {
+	c := newTestClock(t, page.Clock{Type: "clock"})
+	if got := c.Text(clockStart); got != "09:26" {
+		t.Errorf("clock Text() = %q, want %q", got, "09:26")
+	}
+	c = newTestClock(t, page.Clock{Type: "date", Format: "2006-01-02"})
+	if got := c.Text(clockStart); got != "2026-03-14" {
+		t.Errorf("date Text() = %q, want %q", got, "2026-03-14")
+	}
+}
+
+func TestCountdown(t *testing.T) {
+	c := newTestClock(t, page.Clock{Type: "countdown", Target: "2026-03-14 09:30:00"})
+	if got := c.Text(clockStart); got != "03:07" {
+		t.Errorf("Text() = %q, want %q", got, "03:07")
+	}
+	if c.Tick(clockStart) {
+		t.Errorf("Tick() before the target fired")
+	}
+	target := clockStart.Add(187 * time.Second)
+	if !c.Tick(target) {
+		t.Errorf("Tick() at the target did not fire")
+	}
+	if c.Tick(target.Add(time.Second)) {
+		t.Errorf("Tick() after the target fired again")
+	}
+	if got := c.Text(target.Add(time.Minute)); got != "00:00" {
+		t.Errorf("Text() after the target = %q, want %q", got, "00:00")
+	}
+}
+
+func TestStopwatch(t *testing.T) {
+	c := newTestClock(t, page.Clock{Type: "stopwatch"})
+	if !c.Interactive() {
+		t.Fatal("stopwatch is not interactive")
+	}
+	c.Toggle(clockStart)
+	c.Toggle(clockStart.Add(10 * time.Second))
+	// Paused time does not count.
+	c.Toggle(clockStart.Add(time.Minute))
+	if got := c.Text(clockStart.Add(time.Minute + 5*time.Second)); got != "00:15" {
+		t.Errorf("Text() = %q, want %q", got, "00:15")
+	}
+	c.Reset()
+	if got := c.Text(clockStart.Add(2 * time.Minute)); got != "00:00" {
+		t.Errorf("Text() after Reset() = %q, want %q", got, "00:00")
+	}
+}
+
+func TestPomodoro(t *testing.T) {
+	c := newTestClock(t, page.Clock{
+		Type:      "pomodoro",
+		Work:      25 * time.Minute,
+		Break:     5 * time.Minute,
+		LongBreak: 15 * time.Minute,
+		Rounds:    2,
+	})
+	c.Toggle(clockStart)
+	if got := c.Fill(); got != defaultWorkFill {
+		t.Errorf("Fill() while working = %q, want %q", got, defaultWorkFill)
+	}
+	// Every phase ends on time even when the tick comes late, and every
+	// second break is a long one.
+	now := clockStart
+	for i, phase := range []struct {
+		length time.Duration
+		fill   string
+	}{
+		{25 * time.Minute, defaultBreakFill},
+		{5 * time.Minute, defaultWorkFill},
+		{25 * time.Minute, defaultBreakFill},
+		{15 * time.Minute, defaultWorkFill},
+	} {
+		now = now.Add(phase.length)
+		if c.Tick(now.Add(-time.Second)) {
+			t.Fatalf("phase %d ended early", i)
+		}
+		if !c.Tick(now.Add(500 * time.Millisecond)) {
+			t.Fatalf("phase %d did not end", i)
+		}
+		if got := c.Fill(); got != phase.fill {
+			t.Errorf("Fill() after phase %d = %q, want %q", i, got, phase.fill)
+		}
+	}
+	if got := c.Text(now.Add(time.Minute)); got != "24:00" {
+		t.Errorf("Text() = %q, want %q", got, "24:00")
+	}
+}
@@ -0,0 +1,50 @@
+package widget
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type command struct {
+	args    []string
+	max     float64
+	timeout time.Duration
+}
+
+func newCommand(args []string, max float64, timeout time.Duration) (*command, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no command specified")
+	}
+	return &command{args: args, max: max, timeout: timeout}, nil
+}
+
+// Read runs the command and uses the first number it prints as the value.
+func (c *command) Read() (Reading, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, c.args[0], c.args[1:]...).Output()
+	if err != nil {
+		return Reading{}, fmt.Errorf("command %s failed: %w", c.args[0], err)
+	}
+	for _, field := range strings.Fields(string(output)) {
+		value, err := strconv.ParseFloat(strings.TrimSuffix(field, "%"), 64)
+		if err != nil {
+			continue
+		}
+		ratio := 0.0
+		if c.max > 0 {
+			ratio = clamp(value / c.max)
+		}
+		return Reading{
+			Value: value,
+			Ratio: ratio,
+			Text:  strconv.FormatFloat(value, 'f', -1, 64),
+		}, nil
+	}
+	return Reading{}, fmt.Errorf("command %s printed no number", c.args[0])
+}
@@ -0,0 +1,65 @@
+package widget
+
+import "slices"
+
+const defaultHistorySize = 30
+
+// History keeps the most recent readings of a metric for charts.
+type History struct {
+	size     int
+	readings []Reading
+}
+
+func NewHistory(size int) *History {
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+	return &History{size: size, readings: make([]Reading, 0, size)}
+}
+
+func (h *History) Add(r Reading) {
+	if len(h.readings) == h.size {
+		copy(h.readings, h.readings[1:])
+		h.readings = h.readings[:h.size-1]
+	}
+	h.readings = append(h.readings, r)
+}
+
+// Clone returns a copy of the history that later readings do not change.
+func (h *History) Clone() *History {
+	c := *h
+	c.readings = slices.Clone(h.readings)
+	return &c
+}
+
+// Size returns the number of readings kept.
+func (h *History) Size() int {
+	return h.size
+}
+
+// Points returns the readings as ratios from 0 to 1, oldest first. With
+// autoScale the values are scaled to the largest one in the history instead
+// of using the ratio reported by the metric.
+func (h *History) Points(autoScale bool) []float64 {
+	points := make([]float64, len(h.readings))
+	if !autoScale {
+		for i, r := range h.readings {
+			points[i] = r.Ratio
+		}
+		return points
+	}
+
+	peak := 0.0
+	for _, r := range h.readings {
+		if r.Value > peak {
+			peak = r.Value
+		}
+	}
+	if peak <= 0 {
+		return points
+	}
+	for i, r := range h.readings {
+		points[i] = clamp(r.Value / peak)
+	}
+	return points
+}
@@ -0,0 +1,264 @@
+package widget
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	procStat    = "/proc/stat"
+	procMeminfo = "/proc/meminfo"
+	procLoadavg = "/proc/loadavg"
+	procNetDev  = "/proc/net/dev"
+
+	defaultNetworkMax = 12_500_000 // 100 Mbit/s
+)
+
+type cpu struct {
+	idle  uint64
+	total uint64
+}
+
+func newCPU() (*cpu, error) {
+	c := &cpu{}
+	idle, total, err := readCPUTimes()
+	if err != nil {
+		return nil, err
+	}
+	c.idle, c.total = idle, total
+	return c, nil
+}
+
+// Read returns the CPU usage since the previous call.
+func (c *cpu) Read() (Reading, error) {
+	idle, total, err := readCPUTimes()
+	if err != nil {
+		return Reading{}, err
+	}
+	deltaIdle := float64(idle - c.idle)
+	deltaTotal := float64(total - c.total)
+	c.idle, c.total = idle, total
+
+	usage := 0.0
+	if deltaTotal > 0 {
+		usage = (deltaTotal - deltaIdle) / deltaTotal * 100
+	}
+	return Reading{
+		Value: usage,
+		Ratio: clamp(usage / 100),
+		Text:  fmt.Sprintf("%.0f%%", usage),
+	}, nil
+}
+
+func readCPUTimes() (idle, total uint64, err error) {
+	content, err := os.ReadFile(procStat)
+	if err != nil {
+		return 0, 0, err
+	}
+	return parseCPUTimes(string(content))
+}
+
+// parseCPUTimes sums the times of the cpu line of /proc/stat. Idle time
+// includes iowait.
+func parseCPUTimes(stat string) (idle, total uint64, err error) {
+	fields := strings.Fields(FirstLine(stat))
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, fmt.Errorf("unexpected %s format", procStat)
+	}
+	for i, field := range fields[1:] {
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("cannot parse %s: %w", procStat, err)
+		}
+		total += v
+		// idle and iowait
+		if i == 3 || i == 4 {
+			idle += v
+		}
+	}
+	return idle, total, nil
+}
+
+type memory struct{}
+
+func (m *memory) Read() (Reading, error) {
+	content, err := os.ReadFile(procMeminfo)
+	if err != nil {
+		return Reading{}, err
+	}
+	used, err := parseMemoryUsed(string(content))
+	if err != nil {
+		return Reading{}, err
+	}
+	return Reading{
+		Value: used,
+		Ratio: clamp(used / 100),
+		Text:  fmt.Sprintf("%.0f%%", used),
+	}, nil
+}
+
+// parseMemoryUsed returns the percentage of memory in use from the content
+// of /proc/meminfo.
+func parseMemoryUsed(meminfo string) (float64, error) {
+	var total, available float64
+	for line := range strings.Lines(meminfo) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			total, _ = strconv.ParseFloat(fields[1], 64)
+		case "MemAvailable:":
+			available, _ = strconv.ParseFloat(fields[1], 64)
+		}
+	}
+	if total == 0 {
+		return 0, fmt.Errorf("MemTotal not found in %s", procMeminfo)
+	}
+	return (total - available) / total * 100, nil
+}
+
+type load struct{}
+
+// Read returns the one minute load average. The ratio is relative to the
+// number of CPUs.
+func (l *load) Read() (Reading, error) {
+	content, err := os.ReadFile(procLoadavg)
+	if err != nil {
+		return Reading{}, err
+	}
+	value, err := parseLoadavg(string(content))
+	if err != nil {
+		return Reading{}, err
+	}
+	return Reading{
+		Value: value,
+		Ratio: clamp(value / float64(runtime.NumCPU())),
+		Text:  fmt.Sprintf("%.2f", value),
+	}, nil
+}
+
+// parseLoadavg returns the one minute load average from the content of
+// /proc/loadavg.
+func parseLoadavg(loadavg string) (float64, error) {
+	fields := strings.Fields(FirstLine(loadavg))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("unexpected %s format", procLoadavg)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %s: %w", procLoadavg, err)
+	}
+	return value, nil
+}
+
+type network struct {
+	iface     string
+	direction string
+	max       float64
+	bytes     uint64
+	last      time.Time
+}
+
+func newNetwork(iface, direction string, max float64) (*network, error) {
+	if direction == "" {
+		direction = "rx"
+	}
+	if direction != "rx" && direction != "tx" && direction != "total" {
+		return nil, fmt.Errorf("unknown network direction: %s", direction)
+	}
+	if max <= 0 {
+		max = defaultNetworkMax
+	}
+	n := &network{iface: iface, direction: direction, max: max}
+	bytes, err := n.readBytes()
+	if err != nil {
+		return nil, err
+	}
+	n.bytes, n.last = bytes, time.Now()
+	return n, nil
+}
+
+// Read returns the throughput in bytes per second since the previous call.
+func (n *network) Read() (Reading, error) {
+	bytes, err := n.readBytes()
+	if err != nil {
+		return Reading{}, err
+	}
+	now := time.Now()
+	elapsed := now.Sub(n.last).Seconds()
+	rate := 0.0
+	if elapsed > 0 && bytes >= n.bytes {
+		rate = float64(bytes-n.bytes) / elapsed
+	}
+	n.bytes, n.last = bytes, now
+
+	return Reading{
+		Value: rate,
+		Ratio: clamp(rate / n.max),
+		Text:  formatRate(rate),
+	}, nil
+}
+
+// readBytes returns the counters the network reads from /proc/net/dev.
+func (n *network) readBytes() (uint64, error) {
+	content, err := os.ReadFile(procNetDev)
+	if err != nil {
+		return 0, err
+	}
+	return n.parseBytes(string(content))
+}
+
+// parseBytes sums the counters of the configured interface in the content
+// of /proc/net/dev, or of every interface except loopback when none is set.
+func (n *network) parseBytes(netDev string) (uint64, error) {
+	var sum uint64
+	found := false
+	for line := range strings.Lines(netDev) {
+		name, counters, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		if (n.iface == "" && name == "lo") || (n.iface != "" && name != n.iface) {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			continue
+		}
+		rx, _ := strconv.ParseUint(fields[0], 10, 64)
+		tx, _ := strconv.ParseUint(fields[8], 10, 64)
+		switch n.direction {
+		case "rx":
+			sum += rx
+		case "tx":
+			sum += tx
+		default:
+			sum += rx + tx
+		}
+		found = true
+	}
+	if !found && n.iface != "" {
+		return 0, fmt.Errorf("network interface not found: %s", n.iface)
+	}
+	return sum, nil
+}
+
+func formatRate(rate float64) string {
+	units := []string{"B", "K", "M", "G"}
+	i := 0
+	for rate >= 1000 && i < len(units)-1 {
+		rate /= 1000
+		i++
+	}
+	if rate < 10 && i > 0 {
+		return fmt.Sprintf("%.1f%s/s", rate, units[i])
+	}
+	return fmt.Sprintf("%.0f%s/s", rate, units[i])
+}
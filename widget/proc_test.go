@@ -0,0 +1,154 @@
+package widget
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	statFixture = `cpu  100 5 50 800 45 0 0 0 0 0
+cpu0 50 2 25 400 20 0 0 0 0 0
+intr 12345
+`
+	meminfoFixture = `MemTotal:       16000000 kB
+MemFree:         2000000 kB
+MemAvailable:    4000000 kB
+Buffers:          500000 kB
+`
+	loadavgFixture = "1.25 0.80 0.50 2/345 6789\n"
+	netDevFixture  = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  9000      10    0    0    0     0          0         0     9000      10    0    0    0     0       0          0
+  eth0:  1000      10    0    0    0     0          0         0     2000      20    0    0    0     0       0          0
+ wlan0:   300       3    0    0    0     0          0         0      400       4    0    0    0     0       0          0
+`
+)
+
+func TestParseCPUTimes(t *testing.T) {
+	tests := []struct {
+		name        string
+		stat        string
+		idle, total uint64
+		wantErr     bool
+	}{
+		{name: "cpu line", stat: statFixture, idle: 845, total: 1000},
+		{name: "short line", stat: "cpu 1 2 3\n", wantErr: true},
+		{name: "not the cpu line", stat: "cpu0 1 2 3 4 5\n", wantErr: true},
+		{name: "not a number", stat: "cpu 1 2 x 4 5\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idle, total, err := parseCPUTimes(tt.stat)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseCPUTimes() = %d, %d, want an error", idle, total)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCPUTimes() error: %v", err)
+			}
+			if idle != tt.idle || total != tt.total {
+				t.Errorf("parseCPUTimes() = %d, %d, want %d, %d", idle, total, tt.idle, tt.total)
+			}
+		})
+	}
+}
+
+func TestParseMemoryUsed(t *testing.T) {
+	used, err := parseMemoryUsed(meminfoFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used != 75 {
+		t.Errorf("parseMemoryUsed() = %v, want 75", used)
+	}
+	_, err = parseMemoryUsed("MemFree: 100 kB\n")
+	if err == nil || !strings.Contains(err.Error(), "MemTotal not found") {
+		t.Errorf("parseMemoryUsed() without MemTotal error = %v", err)
+	}
+}
+
+func TestParseLoadavg(t *testing.T) {
+	load, err := parseLoadavg(loadavgFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if load != 1.25 {
+		t.Errorf("parseLoadavg() = %v, want 1.25", load)
+	}
+	for _, loadavg := range []string{"", "high 0.80 0.50\n"} {
+		if _, err := parseLoadavg(loadavg); err == nil {
+			t.Errorf("parseLoadavg(%q) did not fail", loadavg)
+		}
+	}
+}
+
+func TestParseNetworkBytes(t *testing.T) {
+	tests := []struct {
+		name      string
+		iface     string
+		direction string
+		want      uint64
+		wantErr   bool
+	}{
+		{name: "every interface but loopback", direction: "rx", want: 1300},
+		{name: "transmitted", direction: "tx", want: 2400},
+		{name: "total", direction: "total", want: 3700},
+		{name: "one interface", iface: "wlan0", direction: "total", want: 700},
+		{name: "loopback by name", iface: "lo", direction: "rx", want: 9000},
+		{name: "unknown interface", iface: "eth1", direction: "rx", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &network{iface: tt.iface, direction: tt.direction}
+			got, err := n.parseBytes(netDevFixture)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseBytes() = %d, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseBytes() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseBytes() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatRate(t *testing.T) {
+	tests := []struct {
+		rate float64
+		want string
+	}{
+		{rate: 0, want: "0B/s"},
+		{rate: 999, want: "999B/s"},
+		{rate: 1500, want: "1.5K/s"},
+		{rate: 25_000, want: "25K/s"},
+		{rate: 12_500_000, want: "12M/s"},
+		{rate: 3e12, want: "3000G/s"},
+	}
+	for _, tt := range tests {
+		if got := formatRate(tt.rate); got != tt.want {
+			t.Errorf("formatRate(%v) = %q, want %q", tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestFirstLine(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{s: "", want: ""},
+		{s: "3\n", want: "3"},
+		{s: "\n  two words  \nmore\n", want: "two words"},
+	}
+	for _, tt := range tests {
+		if got := FirstLine(tt.s); got != tt.want {
+			t.Errorf("FirstLine(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package widget
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	julianUnixEpoch = 2440587.5
+	julian2000      = 2451545.0
+	// The sun is up when its upper edge rises over the horizon, with
+	// refraction.
+	sunriseAltitude = -0.833
+	earthTilt       = 23.4397
+)
+
+// SunTimes returns the sunrise and sunset of the day at the location, in
+// degrees north and east, with the sunrise equation. During the polar day
+// and night the sun neither rises nor sets: up reports which it is and the
+// times are zero.
+func SunTimes(day time.Time, latitude, longitude float64) (sunrise, sunset time.Time, up bool) {
+	noon := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, day.Location())
+	julian := float64(noon.Unix())/86400 + julianUnixEpoch
+	n := math.Round(julian - julian2000 + 0.0008)
+
+	meanSolarTime := n - longitude/360
+	anomaly := math.Mod(357.5291+0.98560028*meanSolarTime, 360)
+	center := 1.9148*sin(anomaly) + 0.02*sin(2*anomaly) + 0.0003*sin(3*anomaly)
+	eclipticLongitude := math.Mod(anomaly+center+180+102.9372, 360)
+	transit := julian2000 + meanSolarTime + 0.0053*sin(anomaly) - 0.0069*sin(2*eclipticLongitude)
+
+	declination := math.Asin(sin(eclipticLongitude) * sin(earthTilt))
+	cosHourAngle := (sin(sunriseAltitude) - sin(latitude)*math.Sin(declination)) / (cos(latitude) * math.Cos(declination))
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		return time.Time{}, time.Time{}, cosHourAngle < -1
+	}
+	hourAngle := math.Acos(cosHourAngle) * 180 / math.Pi
+	return julianTime(transit-hourAngle/360, day.Location()), julianTime(transit+hourAngle/360, day.Location()), true
+}
+
+func julianTime(julian float64, location *time.Location) time.Time {
+	seconds := (julian - julianUnixEpoch) * 86400
+	return time.Unix(int64(seconds), 0).In(location)
+}
+
+func sin(degrees float64) float64 {
+	return math.Sin(degrees * math.Pi / 180)
+}
+
+func cos(degrees float64) float64 {
+	return math.Cos(degrees * math.Pi / 180)
+}
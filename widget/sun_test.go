@@ -0,0 +1,99 @@
+package widget
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSunTimes(t *testing.T) {
+	utc := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name                string
+		day                 time.Time
+		latitude, longitude float64
+		sunrise, sunset     time.Time
+	}{
+		{
+			name:     "London at midsummer",
+			day:      utc(2024, time.June, 21, 0, 0),
+			latitude: 51.5074, longitude: -0.1278,
+			sunrise: utc(2024, time.June, 21, 3, 43),
+			sunset:  utc(2024, time.June, 21, 20, 21),
+		},
+		{
+			name:     "London at midwinter",
+			day:      utc(2024, time.December, 21, 0, 0),
+			latitude: 51.5074, longitude: -0.1278,
+			sunrise: utc(2024, time.December, 21, 8, 4),
+			sunset:  utc(2024, time.December, 21, 15, 54),
+		},
+		{
+			name:     "equator at the equinox",
+			day:      utc(2024, time.March, 20, 0, 0),
+			latitude: 0, longitude: 0,
+			sunrise: utc(2024, time.March, 20, 6, 4),
+			sunset:  utc(2024, time.March, 20, 18, 11),
+		},
+		{
+			name:     "Sydney in the southern summer",
+			day:      utc(2024, time.January, 15, 0, 0),
+			latitude: -33.8688, longitude: 151.2093,
+			sunrise: utc(2024, time.January, 14, 18, 59),
+			sunset:  utc(2024, time.January, 15, 9, 9),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sunrise, sunset, up := SunTimes(tt.day, tt.latitude, tt.longitude)
+			if !up {
+				t.Fatalf("SunTimes() reports the sun does not rise")
+			}
+			// The sunrise equation is good to a few minutes.
+			const tolerance = 5 * time.Minute
+			if d := sunrise.Sub(tt.sunrise).Abs(); d > tolerance {
+				t.Errorf("sunrise = %v, want %v", sunrise, tt.sunrise)
+			}
+			if d := sunset.Sub(tt.sunset).Abs(); d > tolerance {
+				t.Errorf("sunset = %v, want %v", sunset, tt.sunset)
+			}
+		})
+	}
+}
+
+func TestSunTimesLocation(t *testing.T) {
+	location := time.FixedZone("CEST", 2*60*60)
+	day := time.Date(2024, time.June, 21, 23, 30, 0, 0, location)
+	sunrise, sunset, _ := SunTimes(day, 52.2297, 21.0122)
+	if sunrise.Location() != location || sunset.Location() != location {
+		t.Errorf("SunTimes() times are in %v and %v, want %v", sunrise.Location(), sunset.Location(), location)
+	}
+	// The times are of the day in its own time zone, even late at night.
+	if sunrise.Day() != 21 || sunset.Day() != 21 {
+		t.Errorf("SunTimes() = %v, %v, want times on the 21st", sunrise, sunset)
+	}
+}
+
+func TestSunTimesPolar(t *testing.T) {
+	tests := []struct {
+		name   string
+		day    time.Time
+		wantUp bool
+	}{
+		{name: "polar day", day: time.Date(2024, time.June, 21, 0, 0, 0, 0, time.UTC), wantUp: true},
+		{name: "polar night", day: time.Date(2024, time.December, 21, 0, 0, 0, 0, time.UTC), wantUp: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Tromsø, above the Arctic Circle.
+			sunrise, sunset, up := SunTimes(tt.day, 69.6492, 18.9553)
+			if up != tt.wantUp {
+				t.Errorf("SunTimes() up = %v, want %v", up, tt.wantUp)
+			}
+			if !sunrise.IsZero() || !sunset.IsZero() {
+				t.Errorf("SunTimes() = %v, %v, want zero times", sunrise, sunset)
+			}
+		})
+	}
+}
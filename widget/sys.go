@@ -0,0 +1,107 @@
+package widget
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	sysThermal     = "/sys/class/thermal"
+	sysPowerSupply = "/sys/class/power_supply"
+
+	defaultTemperatureMax = 100
+)
+
+type temperature struct {
+	path string
+	max  float64
+}
+
+// newTemperature finds the thermal zone by its directory name or its type,
+// e.g. "thermal_zone1" or "x86_pkg_temp". The first zone is used when source
+// is empty.
+func newTemperature(source string, max float64) (*temperature, error) {
+	if max <= 0 {
+		max = defaultTemperatureMax
+	}
+	zones, err := filepath.Glob(filepath.Join(sysThermal, "thermal_zone*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, zone := range zones {
+		zoneType, _ := ReadValue(filepath.Join(zone, "type"))
+		if source == "" || source == filepath.Base(zone) || source == zoneType {
+			return &temperature{path: filepath.Join(zone, "temp"), max: max}, nil
+		}
+	}
+	return nil, fmt.Errorf("thermal zone not found: %q", source)
+}
+
+func (t *temperature) Read() (Reading, error) {
+	value, err := ReadValue(t.path)
+	if err != nil {
+		return Reading{}, err
+	}
+	milli, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return Reading{}, fmt.Errorf("cannot parse %s: %w", t.path, err)
+	}
+	celsius := milli / 1000
+	return Reading{
+		Value: celsius,
+		Ratio: clamp(celsius / t.max),
+		Text:  fmt.Sprintf("%.0f°C", celsius),
+	}, nil
+}
+
+type battery struct {
+	path string
+}
+
+// newBattery finds the power supply by name, e.g. "BAT0". The first battery
+// is used when source is empty.
+func newBattery(source string) (*battery, error) {
+	if source != "" {
+		path := filepath.Join(sysPowerSupply, source)
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("battery not found: %s", source)
+		}
+		return &battery{path: path}, nil
+	}
+	supplies, err := os.ReadDir(sysPowerSupply)
+	if err != nil {
+		return nil, err
+	}
+	for _, supply := range supplies {
+		path := filepath.Join(sysPowerSupply, supply.Name())
+		supplyType, _ := ReadValue(filepath.Join(path, "type"))
+		if supplyType == "Battery" {
+			return &battery{path: path}, nil
+		}
+	}
+	return nil, fmt.Errorf("no battery found in %s", sysPowerSupply)
+}
+
+func (b *battery) Read() (Reading, error) {
+	value, err := ReadValue(filepath.Join(b.path, "capacity"))
+	if err != nil {
+		return Reading{}, err
+	}
+	capacity, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return Reading{}, fmt.Errorf("cannot parse battery capacity: %w", err)
+	}
+	text := fmt.Sprintf("%.0f%%", capacity)
+	status, _ := ReadValue(filepath.Join(b.path, "status"))
+	if strings.EqualFold(status, "Charging") {
+		text += "+"
+	}
+	return Reading{
+		Value: capacity,
+		Ratio: clamp(capacity / 100),
+		Text:  text,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package widget
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"angrysoft.ovh/angry-deck/page"
+)
+
+// Reading is a single sample taken from a metric.
+type Reading struct {
+	Value float64
+	Ratio float64
+	Text  string
+}
+
+type Metric interface {
+	Read() (Reading, error)
+}
+
+func New(cfg page.Widget) (Metric, error) {
+	switch cfg.Type {
+	case "cpu":
+		return newCPU()
+	case "memory":
+		return &memory{}, nil
+	case "load":
+		return &load{}, nil
+	case "temperature":
+		return newTemperature(cfg.Source, cfg.Max)
+	case "battery":
+		return newBattery(cfg.Source)
+	case "network":
+		return newNetwork(cfg.Source, cfg.Direction, cfg.Max)
+	case "command":
+		return newCommand(cfg.Command, cfg.Max, cfg.RefreshInterval())
+	default:
+		return nil, fmt.Errorf("unknown widget type: %s", cfg.Type)
+	}
+}
+
+func clamp(ratio float64) float64 {
+	if ratio < 0 {
+		return 0
+	}
+	if ratio > 1 {
+		return 1
+	}
+	return ratio
+}
+
+// ReadValue returns the content of a one value file, like those in /sys,
+// without the surrounding whitespace.
+func ReadValue(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// FirstLine returns the first non-empty line of s without the surrounding
+// whitespace.
+func FirstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
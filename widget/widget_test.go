@@ -0,0 +1,72 @@
+package widget
+
+import (
+	"testing"
+	"time"
+
+	"angrysoft.ovh/angry-deck/page"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     page.Widget
+		wantErr string
+	}{
+		{name: "memory", cfg: page.Widget{Type: "memory"}},
+		{name: "unknown type", cfg: page.Widget{Type: "disk"}, wantErr: "unknown widget type: disk"},
+		{name: "network direction", cfg: page.Widget{Type: "network", Direction: "up"}, wantErr: "unknown network direction: up"},
+		{name: "command without arguments", cfg: page.Widget{Type: "command"}, wantErr: "no command specified"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("New() error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("New() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCommandRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		max     float64
+		want    Reading
+		wantErr bool
+	}{
+		{name: "first number", args: []string{"echo", "volume 42% 7"}, max: 100, want: Reading{Value: 42, Ratio: 0.42, Text: "42"}},
+		{name: "no maximum", args: []string{"echo", "1.5"}, want: Reading{Value: 1.5, Text: "1.5"}},
+		{name: "above the maximum", args: []string{"echo", "20"}, max: 10, want: Reading{Value: 20, Ratio: 1, Text: "20"}},
+		{name: "no number", args: []string{"echo", "none"}, wantErr: true},
+		{name: "failing command", args: []string{"false"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := newCommand(tt.args, tt.max, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := c.Read()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Read() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Read() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}